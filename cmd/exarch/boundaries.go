@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const checkBoundariesDesc = `Checks the calls between the layers of the project declared in a JSON
+config and prints every call that crosses a boundary. Exits with 1 when
+there are any, so it can be run in CI, and with 2 when the config can't
+be read or the project can't be checked.
+
+  {
+    "layers": [
+      {
+        "name": "web",
+        "modules": ["MyAppWeb", "MyAppWeb.*"],
+        "allow": ["MyApp.*"],
+        "deny": ["MyApp.Repo", "MyApp.*.Schemas.*"]
+      }
+    ]
+  }
+
+A module belongs to the first layer with a pattern matching it, where *
+matches any characters including dots. A layer may always call its own
+modules and modules outside of the project like Enum. When allow is
+given the layer may only call the project modules it lists. deny lists
+modules the layer may never call, and wins over allow.
+
+Calls are resolved through aliases and imports the same way as refs.`
+
+// boundariesErrorCode is the exit code when the boundaries couldn't be checked, so CI can
+// tell a broken config from calls crossing a boundary
+const boundariesErrorCode = 2
+
+func checkBoundariesCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "check-boundaries",
+		Usage:       "Report calls that cross the boundaries between layers",
+		Description: checkBoundariesDesc,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "path of the config declaring the layers, relative to the project unless absolute",
+				Value: "boundaries.json",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), boundariesErrorCode)
+			}
+
+			input := &search.CheckBoundariesInput{Dir: dir, Config: cmd.String("config")}
+			violations, err := search.CheckBoundaries(input)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Boundaries Error: %v", err), boundariesErrorCode)
+			}
+
+			if violations > 0 {
+				return cli.Exit(fmt.Sprintf("Found %d boundary violations", violations), 1)
+			}
+
+			return nil
+		},
+	}
+}
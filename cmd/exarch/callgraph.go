@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const callGraphDesc = `Prints the graph of calls between the functions of the project, with
+each function given as Module.function/arity. Local, remote and
+imported calls, captures and defdelegates are followed through aliases.
+Calls to functions outside of the project are left out.
+
+Use --module to only keep calls between modules with a prefix, and
+--root to only keep the functions reachable from a function, up to
+--depth calls away. --callers follows the calls made to the root
+instead, to see everything a change to it could affect.
+
+The graph is printed for Graphviz by default, use --format mermaid or
+--format json for the other formats.`
+
+func callGraphCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "callgraph",
+		Usage:       "Export the graph of function calls",
+		Description: callGraphDesc,
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "module",
+				Usage: "only keep calls between modules with these prefixes, eg. --module MyApp.Accounts",
+			},
+			&cli.StringFlag{
+				Name:  "root",
+				Usage: "only keep functions reachable from a Module.function/arity",
+			},
+			&cli.IntFlag{
+				Name:  "depth",
+				Usage: "how many calls away from --root to go, 0 for no limit",
+			},
+			&cli.BoolFlag{
+				Name:  "callers",
+				Usage: "follow the calls made to --root instead of the calls it makes",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "dot, mermaid or json",
+				Value: "dot",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			if cmd.Int("depth") < 0 {
+				return cli.Exit("Input Error: --depth can't be negative", 1)
+			}
+
+			if cmd.String("root") == "" && (cmd.IsSet("depth") || cmd.Bool("callers")) {
+				return cli.Exit("Input Error: --depth and --callers need a --root", 1)
+			}
+
+			input := &search.CallGraphInput{
+				Dir:     dir,
+				Modules: cmd.StringSlice("module"),
+				Root:    cmd.String("root"),
+				Depth:   int(cmd.Int("depth")),
+				Callers: cmd.Bool("callers"),
+				Format:  cmd.String("format"),
+			}
+			if err := search.CallGraph(input); err != nil {
+				return cli.Exit(fmt.Sprintf("Call Graph Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
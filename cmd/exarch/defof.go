@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const defOfDesc = `Prints where the symbol under the cursor is defined. Remote and local
+calls go to the function, aliases go to the module, structs go to the
+defstruct and attributes go to where they are set.
+
+LINE and COL start at 1, and the definitions are printed with lines and
+columns starting at 1 so they can be opened in an editor. This differs
+from search, refs, lint and the other commands, which print rows
+starting at 0.`
+
+func defOfCommand() *cli.Command {
+	var position string
+
+	return &cli.Command{
+		Name:        "def-of",
+		Usage:       "Find the definition of the symbol at a position",
+		ArgsUsage:   "FILE:LINE:COL",
+		Description: defOfDesc,
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:        "position",
+				Destination: &position,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			file, line, col, err := parsePosition(position)
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			if err := search.DefinitionOf(dir, file, line, col); err != nil {
+				return cli.Exit(fmt.Sprintf("Definition Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
+
+// split FILE:LINE:COL into its parts
+func parsePosition(position string) (string, uint32, uint32, error) {
+	parts := strings.Split(position, ":")
+	if len(parts) < 3 {
+		return "", 0, 0, fmt.Errorf("expected FILE:LINE:COL, got %q", position)
+	}
+
+	line, err := strconv.ParseUint(parts[len(parts)-2], 10, 32)
+	if err != nil || line == 0 {
+		return "", 0, 0, fmt.Errorf("invalid line in %q", position)
+	}
+
+	col, err := strconv.ParseUint(parts[len(parts)-1], 10, 32)
+	if err != nil || col == 0 {
+		return "", 0, 0, fmt.Errorf("invalid column in %q", position)
+	}
+
+	return strings.Join(parts[:len(parts)-2], ":"), uint32(line), uint32(col), nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const depsGraphDesc = `Prints the dependencies between the modules of the project, similar to
+mix xref graph but without compiling. Remote calls, aliases, imports,
+use, require and structs are followed through aliases.
+
+Dependencies are marked as compile time when the module has to be
+recompiled when the dependency changes: use, require, import, calls to
+macros and calls made in the module body outside of functions. Every
+other dependency is a runtime dependency.
+
+Cycles between modules are listed after the dependencies. Use
+--format dot or --format json for the other formats.`
+
+func depsGraphCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "deps-graph",
+		Usage:       "Show the dependencies between modules and any cycles",
+		Description: depsGraphDesc,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "text, dot or json",
+				Value: "text",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			input := &search.DepsGraphInput{Dir: dir, Format: cmd.String("format")}
+			if err := search.DepsGraph(input); err != nil {
+				return cli.Exit(fmt.Sprintf("Deps Graph Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
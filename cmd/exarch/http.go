@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const httpDesc = `Serves a page for browsing search results, along with a JSON API
+used by the page:
+
+  GET /search?mode=fncall&q=Repo.update
+
+The search flags are given as query params, eg. sigil=r,H or
+literal_only=true. Results are paginated with page and per_page,
+which defaults to 50.
+
+The project is indexed once and kept up to date, so searches don't
+parse the whole project again.`
+
+func httpCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "http",
+		Usage:       "Serve a JSON search API and a page for browsing results",
+		Description: httpDesc,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "addr",
+				Usage: "the address to listen on",
+				Value: "127.0.0.1:7777",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			if err := search.ListenHTTP(ctx, dir, cmd.String("addr")); err != nil {
+				return cli.Exit(fmt.Sprintf("HTTP Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
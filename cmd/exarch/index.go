@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const indexDesc = `Builds an index of the modules, definitions, calls, aliases and strings
+of every file under .exarch/ in the current directory. Once a project
+has an index the commands built on it, like refs, def-of, callgraph
+and unused, only parse files that have changed since the last run.
+
+Searches only use the index in some modes:
+
+  fncall      answered from the index, except with --doctests
+  str, doc    only files with a string containing the terms are
+              parsed, except with --undocumented
+
+The other modes (impl, protocol, comment, todo, doctest, test, query
+and pattern) parse every file.
+
+Run it again at any time to bring the index up to date, or delete
+.exarch/ to stop using it.`
+
+func indexCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "index",
+		Usage:       "Build or update the on disk index",
+		Description: indexDesc,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			if err := search.BuildIndex(dir); err != nil {
+				return cli.Exit(fmt.Sprintf("Index Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
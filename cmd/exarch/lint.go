@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const lintAliasesDesc = `Checks the aliases in every file of the project and reports:
+  - unused     aliases never referenced in the module they're declared in
+  - duplicate  aliases declared more than once
+  - shadowed   aliases with the same name as an earlier alias, usually
+               from as:
+  - groupable  aliases with a common prefix that could be written as
+               alias Prefix.{A, B}
+
+Exits with 1 when any are found, so it can be run in CI.`
+
+func lintCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "lint",
+		Usage: "Check the project for common problems",
+		Commands: []*cli.Command{
+			{
+				Name:        "aliases",
+				Usage:       "Report unused, duplicate, shadowed and groupable aliases",
+				Description: lintAliasesDesc,
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					dir, err := os.Getwd()
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+					}
+
+					lints, err := search.LintAliases(dir)
+					if err != nil {
+						return cli.Exit(fmt.Sprintf("Lint Error: %v", err), 1)
+					}
+
+					if lints > 0 {
+						return cli.Exit(fmt.Sprintf("Found %d alias problems", lints), 1)
+					}
+
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const lspDesc = `Runs a language server over stdin and stdout for editors. It offers
+workspace symbols, document symbols, go to definition and find
+references, along with an exarch/search request taking the search
+mode, query and search options, eg.
+
+  {"mode": "str", "query": "Hello", "sigils": ["r"]}
+
+The project is indexed when the editor connects and kept up to date
+as files change. Files open in the editor are indexed as they're
+edited, without needing to be saved.`
+
+func lspCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "lsp",
+		Usage:       "Run a language server over stdio",
+		Description: lspDesc,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			if err := search.ServeLSP(dir, os.Stdin, os.Stdout); err != nil {
+				return cli.Exit(fmt.Sprintf("LSP Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
@@ -16,9 +16,47 @@ SEARCH_MODE can be one of:
 1. fncall - Search for function calls. This searches partial matches,
             and is able to handle aliases if given a fully qualified
             function. Eg. TestApp.Users.process can search for
-            Users.process if TestApp.Users has been aliased.
-2. str - Search inside of strings.
-3. doc - search inside of documentation.`
+            Users.process if TestApp.Users has been aliased. Use
+            --doctests to also search the iex> examples in docs.
+2. str - Search inside of strings and sigils. Use --sigil to only
+         search specific sigils, --literal-only or --interpolations
+         to only search the text or code of interpolated strings and
+         --charlists to also search charlists.
+3. doc - search inside of documentation from @moduledoc, @doc,
+         @typedoc and @shortdoc, showing what each doc belongs to.
+         Use --undocumented to report public functions without docs,
+         SEARCH is then optional and filters by function name.
+4. impl - Search for modules implementing a behaviour with @behaviour
+          or use. Lists the @impl callbacks of each module and flags
+          any that aren't defined by the behaviour when its source is
+          in the project.
+5. protocol - Search for protocol definitions and implementations
+              from defimpl or @derive. Searching for a protocol lists
+              its implementations, searching for a type lists the
+              protocols it implements.
+6. comment - Search inside of # comments.
+7. todo - Report TODO, FIXME, HACK and XXX comments grouped by module.
+          SEARCH is optional and filters by the text or author of
+          markers like TODO(alice):.
+8. doctest - Search the iex> examples and expected results in @doc
+             and @moduledoc.
+9. test - Search ExUnit tests by describe and test name, showing the
+          module under test, tags and setups of each test. SEARCH is
+          optional. Use --tag to only find tests with a tag and --calls
+          to only find tests that call a matching function.
+10. query - Run a tree-sitter query, printing each capture with its
+            line, column and capture name. #eq? and #match? predicates
+            are supported. Use --query-file to read the query from a
+            file instead of SEARCH.
+11. pattern - Search for code matching an elixir pattern, eg.
+              'Repo.get!($SCHEMA, $ID)'. $NAME metavariables match any
+              code and are printed with each match, $_ matches anything
+              without being printed. Modules are compared after resolving
+              aliases and do blocks match if they contain the pattern's
+              expressions or clauses in order.
+
+The search flags only apply to the modes they are listed with above,
+giving one to any other mode is an error.`
 
 func main() {
 	var searchMode string
@@ -29,6 +67,61 @@ func main() {
 		Usage:       "Semantic Elixir Search",
 		ArgsUsage:   "SEARCH_MODE SEARCH",
 		Description: desc,
+		Commands: []*cli.Command{
+			refsCommand(),
+			defOfCommand(),
+			indexCommand(),
+			watchCommand(),
+			lspCommand(),
+			serveCommand(),
+			httpCommand(),
+			rewriteCommand(),
+			renameModuleCommand(),
+			renameFnCommand(),
+			callGraphCommand(),
+			depsGraphCommand(),
+			checkBoundariesCommand(),
+			unusedCommand(),
+			lintCommand(),
+		},
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "sigil",
+				Usage: "only search sigils with these names in str mode, eg. --sigil r,H",
+			},
+			&cli.BoolFlag{
+				Name:  "literal-only",
+				Usage: "ignore the code inside of string interpolations in str mode",
+			},
+			&cli.BoolFlag{
+				Name:  "interpolations",
+				Usage: "only search the code inside of string interpolations in str mode",
+			},
+			&cli.BoolFlag{
+				Name:  "charlists",
+				Usage: "include charlists in str mode",
+			},
+			&cli.BoolFlag{
+				Name:  "undocumented",
+				Usage: "report public functions without docs in doc mode",
+			},
+			&cli.BoolFlag{
+				Name:  "doctests",
+				Usage: "also search iex> examples in docs in fncall mode",
+			},
+			&cli.StringSliceFlag{
+				Name:  "tag",
+				Usage: "only find tests with these tags in test mode, eg. --tag integration",
+			},
+			&cli.StringFlag{
+				Name:  "calls",
+				Usage: "only find tests calling a matching function in test mode, eg. --calls Repo.update",
+			},
+			&cli.StringFlag{
+				Name:  "query-file",
+				Usage: "read the query from a file in query mode",
+			},
+		},
 		Arguments: []cli.Argument{
 			&cli.StringArg{
 				Name:        "search_mode",
@@ -40,29 +133,15 @@ func main() {
 			},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			// make sure the search mode is valid
-			var searchType search.SearchType
-			switch searchMode {
-			case "fncall":
-				searchType = search.SearchTypeFnCall
-			case "str":
-				searchType = search.SearchTypeStr
-			case "doc":
-				searchType = search.SearchTypeDoc
-			default:
-				return cli.Exit("Invalid SEARCH_MODE, use --help for instructions", 1)
-			}
-
-			if searchTerms == "" {
-				return cli.Exit("Can't use empty search terms, use --help for instructions", 1)
+			input, err := parseSearch(cmd, searchMode, searchTerms)
+			if err != nil {
+				return err
 			}
 
-			input, err := buildInput(searchType, searchTerms)
-			if err != nil {
-				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			if err := search.Search(input); err != nil {
+				return cli.Exit(fmt.Sprintf("Search Error: %v", err), 1)
 			}
 
-			search.Search(input)
 			return nil
 		},
 	}
@@ -73,16 +152,97 @@ func main() {
 
 }
 
-func buildInput(searchType search.SearchType, searchTerms string) (*search.SearchInput, error) {
+// modeFlags are the search flags and the only search mode each of them applies to
+var modeFlags = []struct {
+	name string
+	mode string
+}{
+	{"sigil", "str"},
+	{"literal-only", "str"},
+	{"interpolations", "str"},
+	{"charlists", "str"},
+	{"undocumented", "doc"},
+	{"doctests", "fncall"},
+	{"tag", "test"},
+	{"calls", "test"},
+	{"query-file", "query"},
+}
+
+// checkModeFlags makes sure the flags given apply to the search mode, rather than being
+// silently ignored
+func checkModeFlags(cmd *cli.Command, searchType search.SearchType) error {
+	for _, flag := range modeFlags {
+		if mode, _ := search.ParseSearchType(flag.mode); cmd.IsSet(flag.name) && mode != searchType {
+			return fmt.Errorf("--%s can only be used in %s mode", flag.name, flag.mode)
+		}
+	}
+
+	return nil
+}
+
+func buildInput(cmd *cli.Command, searchType search.SearchType, searchTerms string) (*search.SearchInput, error) {
+	if err := checkModeFlags(cmd, searchType); err != nil {
+		return nil, err
+	}
+
+	if cmd.Bool("literal-only") && cmd.Bool("interpolations") {
+		return nil, fmt.Errorf("--literal-only and --interpolations can't be used together")
+	}
+
 	dir, err := os.Getwd()
 	if err != nil {
 		panic(err)
 	}
 
+	// queries can be long, so they can be kept in a file
+	if queryFile := cmd.String("query-file"); queryFile != "" {
+		if searchTerms != "" {
+			return nil, fmt.Errorf("give the query as SEARCH or with --query-file, not both")
+		}
+
+		query, err := os.ReadFile(queryFile)
+		if err != nil {
+			return nil, err
+		}
+		searchTerms = string(query)
+	}
+
 	input := &search.SearchInput{
 		SearchType:  searchType,
 		SearchTerms: searchTerms,
 		Dir:         dir,
+		Sigils:      cmd.StringSlice("sigil"),
+
+		LiteralOnly:    cmd.Bool("literal-only"),
+		Interpolations: cmd.Bool("interpolations"),
+		Charlists:      cmd.Bool("charlists"),
+		Undocumented:   cmd.Bool("undocumented"),
+		Doctests:       cmd.Bool("doctests"),
+
+		Tags:  cmd.StringSlice("tag"),
+		Calls: cmd.String("calls"),
+	}
+
+	return input, nil
+}
+
+// parseSearch checks the search mode and terms given on the command line and builds the
+// input for the search.
+func parseSearch(cmd *cli.Command, searchMode, searchTerms string) (*search.SearchInput, error) {
+	// make sure the search mode is valid
+	searchType, err := search.ParseSearchType(searchMode)
+	if err != nil {
+		return nil, cli.Exit("Invalid SEARCH_MODE, use --help for instructions", 1)
+	}
+
+	input, err := buildInput(cmd, searchType, searchTerms)
+	if err != nil {
+		return nil, cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+	}
+
+	// reports can be run without filtering
+	if input.SearchTerms == "" && !input.IsReport() {
+		return nil, cli.Exit("Can't use empty search terms, use --help for instructions", 1)
 	}
 
 	return input, nil
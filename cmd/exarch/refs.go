@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const refsDesc = `Finds every reference to a function across the project, including
+local calls, imported calls, pipes, captures like &Mod.fun/1,
+defdelegates and apply(Mod, :fun, args). Each reference shows the
+function making it.
+
+MFA is a fully qualified Module.function/arity. The arity can be left
+off to find references to every arity. Calls with any arity a function
+with default arguments accepts are references to it.`
+
+func refsCommand() *cli.Command {
+	var mfa string
+
+	return &cli.Command{
+		Name:        "refs",
+		Usage:       "Find references to a function",
+		ArgsUsage:   "MFA",
+		Description: refsDesc,
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:        "mfa",
+				Destination: &mfa,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if mfa == "" {
+				return cli.Exit("Missing MFA, use --help for instructions", 1)
+			}
+
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			if err := search.Refs(dir, mfa); err != nil {
+				return cli.Exit(fmt.Sprintf("Refs Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const renameModuleDesc = `Renames the module OLD to NEW across the project, along with any module
+nested under it. The defmodule, aliases including grouped aliases like
+alias MyApp.{Users, Admins}, remote calls, structs, @behaviour, use,
+import, require and atoms like :"Elixir.MyApp.Users" are all updated.
+References through an alias keep using the alias when they can. Module
+names built at runtime, like Module.concat or interpolated atoms, are
+not found.
+
+Use --move to also move the file defining the module to the path
+matching its new name, eg. lib/my_app/users.ex for MyApp.Users, and
+--dry-run to print the changes as a unified diff without writing them.`
+
+func renameModuleCommand() *cli.Command {
+	var from string
+	var to string
+
+	return &cli.Command{
+		Name:        "rename-module",
+		Usage:       "Rename a module across the project",
+		ArgsUsage:   "OLD NEW",
+		Description: renameModuleDesc,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "move",
+				Usage: "move the file defining the module to match its new name",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "print a diff of the changes instead of writing them",
+			},
+		},
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:        "old",
+				Destination: &from,
+			},
+			&cli.StringArg{
+				Name:        "new",
+				Destination: &to,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if from == "" || to == "" || cmd.Args().Len() > 0 {
+				return cli.Exit("Give the OLD and NEW module names, use --help for instructions", 1)
+			}
+
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			input := &search.RenameInput{
+				Dir:    dir,
+				From:   from,
+				To:     to,
+				Move:   cmd.Bool("move"),
+				DryRun: cmd.Bool("dry-run"),
+			}
+			if err := search.RenameModule(input); err != nil {
+				return cli.Exit(fmt.Sprintf("Rename Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
+
+const renameFnDesc = `Renames the function MFA, given as Module.function/arity, to NAME across
+the project. The definition clauses, local and remote calls resolved
+through aliases, calls to imported functions, captures like &Mod.fun/2,
+defdelegate targets, import only: and except: options and @specs are
+updated. Other arities of the function are left alone, except for the
+arities a definition with default arguments also defines.
+
+The changes are printed as a unified diff and you are asked before they
+are written. Use --yes to write them without asking, or --dry-run to
+only print the diff.`
+
+func renameFnCommand() *cli.Command {
+	var mfa string
+	var name string
+
+	return &cli.Command{
+		Name:        "rename-fn",
+		Usage:       "Rename a function across the project",
+		ArgsUsage:   "MFA NAME",
+		Description: renameFnDesc,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "print a diff of the changes without writing them",
+			},
+			&cli.BoolFlag{
+				Name:  "yes",
+				Usage: "write the changes without asking",
+			},
+		},
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:        "mfa",
+				Destination: &mfa,
+			},
+			&cli.StringArg{
+				Name:        "name",
+				Destination: &name,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if mfa == "" || name == "" || cmd.Args().Len() > 0 {
+				return cli.Exit("Give the MFA and new NAME of the function, use --help for instructions", 1)
+			}
+
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			input := &search.RenameInput{
+				Dir:    dir,
+				From:   mfa,
+				To:     name,
+				DryRun: cmd.Bool("dry-run"),
+			}
+			if !cmd.Bool("yes") {
+				input.Confirm = confirm
+			}
+
+			if err := search.RenameFunction(input); err != nil {
+				return cli.Exit(fmt.Sprintf("Rename Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
+
+// confirm asks whether to write the changes that were printed
+func confirm() bool {
+	fmt.Print("Apply these changes? [y/N] ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const rewriteDesc = `Replaces code matching PATTERN with REPLACEMENT in every elixir file
+under the current directory, eg.
+
+    exarch rewrite 'Repo.get!($S, $I)' 'Repo.fetch!($S, $I)'
+
+PATTERN is written the same way as in pattern mode, and modules are
+compared after resolving aliases. REPLACEMENT can use the metavariables
+bound by PATTERN. Only the matched code changes, the formatting around
+it is left alone, and every file is written atomically once all of the
+files have been rewritten.
+
+Use --dry-run to print the changes as a unified diff without writing
+them.`
+
+func rewriteCommand() *cli.Command {
+	var pattern string
+	var replacement string
+
+	return &cli.Command{
+		Name:        "rewrite",
+		Usage:       "Rewrite code matching a pattern",
+		ArgsUsage:   "PATTERN REPLACEMENT",
+		Description: rewriteDesc,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "print a diff of the changes instead of writing them",
+			},
+		},
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:        "pattern",
+				Destination: &pattern,
+			},
+			&cli.StringArg{
+				Name:        "replacement",
+				Destination: &replacement,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if pattern == "" || replacement == "" || cmd.Args().Len() > 0 {
+				return cli.Exit("Give a PATTERN and a REPLACEMENT, use --help for instructions", 1)
+			}
+
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			if err := search.Rewrite(dir, pattern, replacement, cmd.Bool("dry-run")); err != nil {
+				return cli.Exit(fmt.Sprintf("Rewrite Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const serveDesc = `Runs a JSON-RPC 2.0 server for tools, reading one request per line
+and writing one response or notification per line. The project is
+indexed once and kept up to date, so requests don't parse the whole
+project again. Methods:
+
+  search      {"mode": "fncall", "query": "Repo.update", ...}
+              takes the search flags in camel case, eg. "literalOnly"
+  refs        {"mfa": "MyApp.Accounts.get_user/1"}
+  outline     {"path": "lib/my_app/accounts.ex"}
+  definition  {"path": "lib/my_app/accounts.ex", "line": 12, "column": 5}
+
+search and refs stream their results as search/result and refs/result
+notifications, one per file, then respond with the number of files
+and matches. Send a $/cancelRequest notification with the id of a
+request to cancel it.`
+
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "serve",
+		Usage:       "Run a JSON-RPC server for tools",
+		Description: serveDesc,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "stdio",
+				Usage: "serve over stdin and stdout",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if !cmd.Bool("stdio") {
+				return cli.Exit("Missing transport, use --stdio", 1)
+			}
+
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			if err := search.Serve(ctx, dir, os.Stdin, os.Stdout); err != nil {
+				return cli.Exit(fmt.Sprintf("Serve Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const unusedDesc = `Lists the public and private functions that are never referenced in the
+project. Local, remote and imported calls, captures, defdelegates and
+apply with a literal module and function name all count as references.
+A function calling itself doesn't.
+
+Some functions are called without a reference in the code, so these are
+never listed:
+  - callbacks marked with @impl and protocol implementations
+  - controller actions, functions with an arity of 2 in modules ending
+    in Controller
+  - functions whose name is called in a template or a ~H sigil
+  - functions defined in .exs files like tests and mix.exs
+
+Use --ignore to skip other functions by Module.function/arity, where *
+matches anything, eg. --ignore "MyApp.*.child_spec/1".`
+
+func unusedCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "unused",
+		Usage:       "List functions that are never referenced",
+		Description: unusedDesc,
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "ignore",
+				Usage: "skip functions matching these Module.function/arity patterns",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			input := &search.UnusedInput{Dir: dir, Ignore: cmd.StringSlice("ignore")}
+			if err := search.Unused(input); err != nil {
+				return cli.Exit(fmt.Sprintf("Unused Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/robmerrell/exarch/internal/search"
+	"github.com/urfave/cli/v3"
+)
+
+const watchDesc = `Watches the current directory for changes to .ex and .exs files and
+keeps the on disk index up to date, creating it if needed. Files that
+change more than once are parsed incrementally.
+
+Give a SEARCH_MODE and SEARCH, with any of the search flags, to run a
+search up front and again after every change, printing the results
+that were removed (-) and added (+). Handy for watching the call
+sites left in a refactor go to zero.
+
+File system events are used when available, use --poll to check for
+changes on an interval instead.`
+
+func watchCommand() *cli.Command {
+	var searchMode string
+	var searchTerms string
+
+	return &cli.Command{
+		Name:        "watch",
+		Usage:       "Keep the index up to date and re-run a search on changes",
+		ArgsUsage:   "[SEARCH_MODE SEARCH]",
+		Description: watchDesc,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "poll",
+				Usage: "poll for changes instead of using file system events",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "how often to poll for changes",
+				Value: time.Second,
+			},
+		},
+		Arguments: []cli.Argument{
+			&cli.StringArg{
+				Name:        "search_mode",
+				Destination: &searchMode,
+			},
+			&cli.StringArg{
+				Name:        "search_terms",
+				Destination: &searchTerms,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return cli.Exit(fmt.Sprintf("Input Error: %v", err), 1)
+			}
+
+			input := &search.WatchInput{
+				Dir:      dir,
+				Poll:     cmd.Bool("poll"),
+				Interval: cmd.Duration("interval"),
+			}
+
+			if searchMode != "" {
+				input.Query, err = parseSearch(cmd, searchMode, searchTerms)
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := search.Watch(ctx, input); err != nil {
+				return cli.Exit(fmt.Sprintf("Watch Error: %v", err), 1)
+			}
+
+			return nil
+		},
+	}
+}
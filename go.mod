@@ -3,6 +3,9 @@ module github.com/robmerrell/exarch
 go 1.24.2
 
 require (
-	github.com/smacker/go-tree-sitter v0.0.0-20240827094217-dd81d9e9be82 // indirect
-	github.com/urfave/cli/v3 v3.3.2 // indirect
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/smacker/go-tree-sitter v0.0.0-20240827094217-dd81d9e9be82
+	github.com/urfave/cli/v3 v3.3.2
 )
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,204 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// BoundariesConfig declares the layers of a project and the modules each layer may call.
+//
+//	{
+//	  "layers": [
+//	    {"name": "web", "modules": ["MyAppWeb.*"], "allow": ["MyApp.*"], "deny": ["MyApp.Repo"]}
+//	  ]
+//	}
+//
+// Module patterns match a module name exactly, with * matching any characters including
+// dots, so MyApp.* matches MyApp.Accounts and MyApp.Accounts.User but not MyAppWeb.
+type BoundariesConfig struct {
+	Layers []Layer `json:"layers"`
+}
+
+// Layer is a group of modules and the rules for the calls made from them. A module
+// belongs to the first layer with a pattern matching it.
+type Layer struct {
+	Name    string   `json:"name"`
+	Modules []string `json:"modules"`
+
+	// Allow lists the project modules the layer may call besides its own modules. When
+	// it's empty every module may be called. Modules outside of the project, like Enum,
+	// are always allowed.
+	Allow []string `json:"allow"`
+
+	// Deny lists the modules the layer may never call, even when they are allowed or in
+	// the layer.
+	Deny []string `json:"deny"`
+
+	modules []*regexp.Regexp
+	allow   []*regexp.Regexp
+	deny    []*regexp.Regexp
+}
+
+// BoundaryViolation is a call made from a layer to a module it may not call
+type BoundaryViolation struct {
+	Ref
+	Layer string
+}
+
+func (v BoundaryViolation) Format() string {
+	return fmt.Sprintf("%s (%s may not call %s)", v.Ref.Format(), v.Layer, v.Module)
+}
+
+// CheckBoundariesInput holds the input for checking the calls made between layers.
+type CheckBoundariesInput struct {
+	Dir string
+
+	// Config is the path of the JSON config declaring the layers, relative to Dir unless
+	// it's absolute.
+	Config string
+}
+
+// CheckBoundaries prints every call in the project in dir that crosses a boundary declared
+// in the config, and returns the number of violations found.
+func CheckBoundaries(input *CheckBoundariesInput) (int, error) {
+	configPath := input.Config
+	if !filepath.IsAbs(configPath) {
+		configPath = filepath.Join(input.Dir, configPath)
+	}
+
+	config, err := loadBoundariesConfig(configPath)
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := indexProject(input.Dir)
+	if err != nil {
+		return 0, err
+	}
+
+	found := findBoundaryViolations(files, config)
+
+	count := 0
+	for _, file := range files {
+		violations := found[file.Path]
+		if len(violations) == 0 {
+			continue
+		}
+
+		relFile, err := filepath.Rel(input.Dir, file.Path)
+		if err != nil {
+			return 0, err
+		}
+
+		fmt.Println(relFile)
+		for _, violation := range violations {
+			fmt.Println(violation.Format())
+		}
+		fmt.Println("")
+
+		count += len(violations)
+	}
+
+	return count, nil
+}
+
+// loadBoundariesConfig reads the config at path and compiles its module patterns
+func loadBoundariesConfig(path string) (*BoundariesConfig, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the boundaries config: %w", err)
+	}
+
+	config := &BoundariesConfig{}
+	if err := json.Unmarshal(contents, config); err != nil {
+		return nil, fmt.Errorf("invalid boundaries config %s: %w", path, err)
+	}
+
+	if len(config.Layers) == 0 {
+		return nil, fmt.Errorf("invalid boundaries config %s: no layers are declared", path)
+	}
+
+	for i := range config.Layers {
+		layer := &config.Layers[i]
+		if layer.Name == "" || len(layer.Modules) == 0 {
+			return nil, fmt.Errorf("invalid boundaries config %s: every layer needs a name and modules", path)
+		}
+
+		layer.modules = compileModulePatterns(layer.Modules)
+		layer.allow = compileModulePatterns(layer.Allow)
+		layer.deny = compileModulePatterns(layer.Deny)
+	}
+
+	return config, nil
+}
+
+// compileModulePatterns turns module patterns into regexes where * matches anything
+func compileModulePatterns(patterns []string) []*regexp.Regexp {
+	compiled := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`)
+		compiled = append(compiled, regexp.MustCompile("^"+expr+"$"))
+	}
+
+	return compiled
+}
+
+func matchesAnyPattern(module string, patterns []*regexp.Regexp) bool {
+	return slices.ContainsFunc(patterns, func(pattern *regexp.Regexp) bool { return pattern.MatchString(module) })
+}
+
+// layer returns the first layer the module belongs to
+func (c *BoundariesConfig) layer(module string) *Layer {
+	for i := range c.Layers {
+		if matchesAnyPattern(module, c.Layers[i].modules) {
+			return &c.Layers[i]
+		}
+	}
+
+	return nil
+}
+
+// findBoundaryViolations finds the references in each file that are made from a layer to a
+// module the layer may not call. Violations are keyed by file path.
+func findBoundaryViolations(files []*FileIndex, config *BoundariesConfig) map[string][]BoundaryViolation {
+	defined := map[string]bool{}
+	for _, file := range files {
+		for _, module := range file.Modules {
+			defined[module.Name] = true
+		}
+	}
+
+	found := map[string][]BoundaryViolation{}
+	for _, file := range files {
+		for _, ref := range file.Refs {
+			from := callerModule(ref.Caller)
+			if ref.Module == "" || ref.Module == from {
+				continue
+			}
+
+			layer := config.layer(from)
+			if layer == nil {
+				continue
+			}
+
+			allowed := true
+			switch {
+			case matchesAnyPattern(ref.Module, layer.deny):
+				allowed = false
+			case len(layer.allow) > 0 && defined[ref.Module]:
+				allowed = matchesAnyPattern(ref.Module, layer.modules) || matchesAnyPattern(ref.Module, layer.allow)
+			}
+
+			if !allowed {
+				found[file.Path] = append(found[file.Path], BoundaryViolation{Ref: ref, Layer: layer.Name})
+			}
+		}
+	}
+
+	return found
+}
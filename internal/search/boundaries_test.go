@@ -0,0 +1,108 @@
+package search
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const boundariesConfig = `{
+  "layers": [
+    {"name": "web", "modules": ["App.Web", "App.Web.*"], "allow": ["App.Accounts"], "deny": ["App.Repo"]},
+    {"name": "contexts", "modules": ["App.*"], "deny": ["App.Web*"]}
+  ]
+}`
+
+var boundariesProject = map[string]string{
+	"boundaries.json": boundariesConfig,
+	"repo.ex": `defmodule App.Repo do
+  def get(id), do: id
+end
+`,
+	"accounts.ex": `defmodule App.Accounts do
+  alias App.Accounts.User
+
+  def get_user(id), do: App.Repo.get(id) |> User.new()
+  def link(user), do: App.Web.Router.path(user)
+end
+
+defmodule App.Accounts.User do
+  def new(id), do: %{id: id}
+end
+`,
+	"web.ex": `defmodule App.Web.UserController do
+  alias App.{Accounts, Repo}
+
+  def show(id) do
+    user = Accounts.get_user(id)
+    Repo.get(id)
+    Enum.count([user])
+    App.Accounts.User.new(id)
+  end
+end
+
+defmodule App.Web.Router do
+  def path(user), do: App.Web.UserController.show(user)
+end
+`,
+}
+
+func TestFindBoundaryViolations(t *testing.T) {
+	dir := writeProjectFiles(t, boundariesProject)
+	config, err := loadBoundariesConfig(filepath.Join(dir, "boundaries.json"))
+	if err != nil {
+		t.Fatalf("load config failed: %v", err)
+	}
+
+	files, err := indexProject(dir)
+	if err != nil {
+		t.Fatalf("index project failed: %v", err)
+	}
+
+	found := findBoundaryViolations(files, config)
+
+	got := []string{}
+	for _, file := range files {
+		for _, violation := range found[file.Path] {
+			got = append(got, filepath.Base(file.Path)+":"+violation.Format())
+		}
+	}
+
+	// calls within a layer and to modules outside of the project are allowed
+	expected := []string{
+		"accounts.ex:4:[App.Accounts.link/1] App.Web.Router.path(user) (contexts may not call App.Web.Router)",
+		"web.ex:5:[App.Web.UserController.show/1] Repo.get(id) (web may not call App.Repo)",
+		"web.ex:7:[App.Web.UserController.show/1] App.Accounts.User.new(id) (web may not call App.Accounts.User)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %#v want %#v", got, expected)
+	}
+}
+
+func TestLoadBoundariesConfigErrors(t *testing.T) {
+	dir := writeProjectFiles(t, map[string]string{
+		"invalid.json": "{",
+		"empty.json":   `{"layers": []}`,
+		"unnamed.json": `{"layers": [{"modules": ["App.*"]}]}`,
+	})
+
+	for _, name := range []string{"missing.json", "invalid.json", "empty.json", "unnamed.json"} {
+		if _, err := loadBoundariesConfig(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCheckBoundariesConfigPath(t *testing.T) {
+	dir := writeProjectFiles(t, boundariesProject)
+
+	// configs outside of the project are given by an absolute path
+	config := filepath.Join(writeProjectFiles(t, map[string]string{"layers.json": boundariesConfig}), "layers.json")
+
+	for _, path := range []string{"boundaries.json", config} {
+		violations, err := CheckBoundaries(&CheckBoundariesInput{Dir: dir, Config: path})
+		if err != nil || violations != 3 {
+			t.Errorf("%s: got %d %v want 3 violations", path, violations, err)
+		}
+	}
+}
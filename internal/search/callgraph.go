@@ -0,0 +1,270 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CallGraphInput holds the input for exporting the call graph of a project.
+type CallGraphInput struct {
+	Dir string
+
+	// Modules only keeps calls between functions in modules with one of these prefixes.
+	Modules []string
+
+	// Root only keeps the functions reachable from a Module.function/arity, or every arity
+	// when the arity is left off, up to Depth calls away. Callers follows the calls made to
+	// the root instead of the calls it makes. A Depth of 0 isn't limited.
+	Root    string
+	Depth   int
+	Callers bool
+
+	// Format is dot, mermaid or json.
+	Format string
+}
+
+// callGraph is a graph of the functions of a project in Module.function/arity form
+type callGraph struct {
+	Nodes []string   `json:"nodes"`
+	Edges []callEdge `json:"edges"`
+}
+
+// callEdge is a function calling another function, Calls times.
+type callEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Calls int    `json:"calls"`
+}
+
+// CallGraph prints the graph of calls between the functions of the project in dir.
+func CallGraph(input *CallGraphInput) error {
+	if !slices.Contains([]string{"dot", "mermaid", "json"}, input.Format) {
+		return fmt.Errorf("unknown format %s, use dot, mermaid or json", input.Format)
+	}
+
+	files, err := indexProject(input.Dir)
+	if err != nil {
+		return err
+	}
+
+	graph := buildCallGraph(files)
+	if len(input.Modules) > 0 {
+		graph = graph.filterModules(input.Modules)
+	}
+
+	if input.Root != "" {
+		graph, err = graph.reachable(input.Root, input.Depth, input.Callers)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch input.Format {
+	case "dot":
+		fmt.Print(graph.dot())
+	case "mermaid":
+		fmt.Print(graph.mermaid())
+	case "json":
+		out, err := marshalJSON(graph)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	}
+
+	return nil
+}
+
+// buildCallGraph builds the graph of calls between functions defined in the project.
+// Calls to a function with default arguments are added to the function's full arity.
+func buildCallGraph(files []*FileIndex) callGraph {
+	defs := map[string][]FuncDef{}
+	for _, file := range files {
+		for _, def := range file.Defs {
+			defs[def.Module+"."+def.Name] = append(defs[def.Module+"."+def.Name], def)
+		}
+	}
+
+	nodes := map[string]bool{}
+	calls := map[callEdge]int{}
+	for _, file := range files {
+		for _, def := range file.Defs {
+			nodes[def.Module+"."+def.Signature()] = true
+		}
+
+		for _, ref := range file.Refs {
+			if ref.Module == "" {
+				continue
+			}
+
+			from := ref.Caller
+			if ref.Kind == "defdelegate" {
+				from = delegateCaller(file, ref)
+			}
+
+			// references made outside of functions aren't a function calling a function
+			if !strings.Contains(from, "/") {
+				continue
+			}
+
+			for _, def := range defs[ref.Module+"."+ref.Name] {
+				if def.HasArity(ref.Arity) {
+					calls[callEdge{From: from, To: def.Module + "." + def.Signature()}]++
+					break
+				}
+			}
+		}
+	}
+
+	graph := callGraph{Nodes: []string{}, Edges: []callEdge{}}
+	for node := range nodes {
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	sort.Strings(graph.Nodes)
+
+	for edge, count := range calls {
+		edge.Calls = count
+		graph.Edges = append(graph.Edges, edge)
+	}
+	sortEdges(graph.Edges)
+
+	return graph
+}
+
+// delegateCaller is the function a defdelegate defines, which calls the function it
+// delegates to.
+func delegateCaller(file *FileIndex, ref Ref) string {
+	for _, def := range file.Defs {
+		if def.Kind == "defdelegate" && def.Line == ref.Line {
+			return def.Module + "." + def.Signature()
+		}
+	}
+
+	return ref.Caller
+}
+
+func sortEdges(edges []callEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
+
+// filterModules keeps the calls between functions in modules with one of the prefixes
+func (g callGraph) filterModules(prefixes []string) callGraph {
+	inModules := func(function string) bool {
+		module := function[:strings.LastIndex(function[:strings.LastIndex(function, "/")], ".")]
+		return slices.ContainsFunc(prefixes, func(prefix string) bool {
+			return module == prefix || strings.HasPrefix(module, prefix+".")
+		})
+	}
+
+	filtered := callGraph{Nodes: []string{}, Edges: []callEdge{}}
+	for _, node := range g.Nodes {
+		if inModules(node) {
+			filtered.Nodes = append(filtered.Nodes, node)
+		}
+	}
+
+	for _, edge := range g.Edges {
+		if inModules(edge.From) && inModules(edge.To) {
+			filtered.Edges = append(filtered.Edges, edge)
+		}
+	}
+
+	return filtered
+}
+
+// reachable keeps the functions reachable from the root within depth calls, following
+// the calls made by each function or, with callers, the calls made to it.
+func (g callGraph) reachable(root string, depth int, callers bool) (callGraph, error) {
+	module, name, arity, err := parseMFA(root)
+	if err != nil {
+		return callGraph{}, err
+	}
+
+	// the functions reached so far, with the number of calls it took to reach them
+	reached := map[string]int{}
+	queue := []string{}
+	for _, node := range g.Nodes {
+		if node == fmt.Sprintf("%s.%s/%d", module, name, arity) || (arity < 0 && strings.HasPrefix(node, module+"."+name+"/")) {
+			reached[node] = 0
+			queue = append(queue, node)
+		}
+	}
+
+	if len(queue) == 0 {
+		return callGraph{}, fmt.Errorf("%s isn't defined in the project", root)
+	}
+
+	edges := []callEdge{}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if depth > 0 && reached[node] >= depth {
+			continue
+		}
+
+		for _, edge := range g.Edges {
+			from, to := edge.From, edge.To
+			if callers {
+				from, to = to, from
+			}
+
+			if from != node {
+				continue
+			}
+
+			edges = append(edges, edge)
+			if _, ok := reached[to]; !ok {
+				reached[to] = reached[node] + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	filtered := callGraph{Nodes: []string{}, Edges: edges}
+	for node := range reached {
+		filtered.Nodes = append(filtered.Nodes, node)
+	}
+	sort.Strings(filtered.Nodes)
+	sortEdges(filtered.Edges)
+
+	return filtered, nil
+}
+
+// dot formats the graph for Graphviz
+func (g callGraph) dot() string {
+	out := &strings.Builder{}
+	out.WriteString("digraph callgraph {\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(out, "  %q;\n", node)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(out, "  %q -> %q;\n", edge.From, edge.To)
+	}
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+// mermaid formats the graph as a Mermaid flowchart. Function names aren't valid ids, so
+// nodes are numbered and labelled with the function.
+func (g callGraph) mermaid() string {
+	ids := map[string]string{}
+	out := &strings.Builder{}
+	out.WriteString("flowchart LR\n")
+	for i, node := range g.Nodes {
+		ids[node] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(out, "  n%d[\"%s\"]\n", i, node)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(out, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+
+	return out.String()
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildCallGraph(t *testing.T) {
+	files, err := indexProject(writeTestProject(t))
+	if err != nil {
+		t.Fatalf("index project failed: %v", err)
+	}
+
+	graph := buildCallGraph(files)
+
+	expected := []callEdge{
+		{From: "TestApp.Accounts.Admin.fetch/1", To: "TestApp.Accounts.Users.get_user!/1", Calls: 1},
+		{From: "TestApp.Accounts.fetch_user!/1", To: "TestApp.Accounts.Users.get_user!/1", Calls: 1},
+		{From: "TestApp.Accounts.load/1", To: "TestApp.Accounts.Users.get_user!/1", Calls: 1},
+		{From: "TestApp.Accounts.load_one/1", To: "TestApp.Accounts.Users.get_user!/1", Calls: 1},
+	}
+	if !reflect.DeepEqual(graph.Edges, expected) {
+		t.Errorf("got %+v want %+v", graph.Edges, expected)
+	}
+
+	if len(graph.Nodes) != 10 {
+		t.Errorf("got %d nodes want 10, %v", len(graph.Nodes), graph.Nodes)
+	}
+}
+
+func TestCallGraphFilters(t *testing.T) {
+	graph := callGraph{
+		Nodes: []string{"A.a/0", "A.B.b/1", "C.c/0", "C.d/2"},
+		Edges: []callEdge{
+			{From: "A.a/0", To: "A.B.b/1", Calls: 2},
+			{From: "A.B.b/1", To: "C.c/0", Calls: 1},
+			{From: "C.c/0", To: "C.d/2", Calls: 1},
+		},
+	}
+
+	filtered := graph.filterModules([]string{"A"})
+	if !reflect.DeepEqual(filtered.Nodes, []string{"A.a/0", "A.B.b/1"}) || len(filtered.Edges) != 1 {
+		t.Errorf("filter modules got %+v", filtered)
+	}
+
+	tests := []struct {
+		root     string
+		depth    int
+		callers  bool
+		expected []string
+	}{
+		{"A.a/0", 0, false, []string{"A.B.b/1", "A.a/0", "C.c/0", "C.d/2"}},
+		{"A.a", 2, false, []string{"A.B.b/1", "A.a/0", "C.c/0"}},
+		{"C.c/0", 1, true, []string{"A.B.b/1", "C.c/0"}},
+	}
+
+	for _, test := range tests {
+		reached, err := graph.reachable(test.root, test.depth, test.callers)
+		if err != nil {
+			t.Fatalf("reachable failed: %v", err)
+		}
+
+		if !reflect.DeepEqual(reached.Nodes, test.expected) || len(reached.Edges) != len(test.expected)-1 {
+			t.Errorf("%s: got %+v want the nodes %v", test.root, reached, test.expected)
+		}
+	}
+
+	if _, err := graph.reachable("A.missing/0", 0, false); err == nil {
+		t.Errorf("expected an error for a root that isn't defined")
+	}
+}
+
+func TestCallGraphFormats(t *testing.T) {
+	graph := callGraph{
+		Nodes: []string{"A.a/0", "B.b?/1"},
+		Edges: []callEdge{{From: "A.a/0", To: "B.b?/1", Calls: 1}},
+	}
+
+	dot := strings.Join([]string{
+		"digraph callgraph {",
+		`  "A.a/0";`,
+		`  "B.b?/1";`,
+		`  "A.a/0" -> "B.b?/1";`,
+		"}",
+		"",
+	}, "\n")
+	if got := graph.dot(); got != dot {
+		t.Errorf("got\n%s\nwant\n%s", got, dot)
+	}
+
+	mermaid := strings.Join([]string{
+		"flowchart LR",
+		`  n0["A.a/0"]`,
+		`  n1["B.b?/1"]`,
+		"  n0 --> n1",
+		"",
+	}, "\n")
+	if got := graph.mermaid(); got != mermaid {
+		t.Errorf("got\n%s\nwant\n%s", got, mermaid)
+	}
+}
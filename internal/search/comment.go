@@ -0,0 +1,148 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+type Todo struct {
+	Marker string // TODO, FIXME, HACK or XXX
+	Author string // optional author from TODO(author):
+	Text   string
+	Line   uint32
+}
+
+// TodoGroup holds all of the todos found inside of a module.
+type TodoGroup struct {
+	Module string
+	Todos  []Todo
+}
+
+func (g TodoGroup) Format() string {
+	module := g.Module
+	if module == "" {
+		module = "(no module)"
+	}
+
+	lines := []string{module}
+	for _, todo := range g.Todos {
+		marker := todo.Marker
+		if todo.Author != "" {
+			marker = fmt.Sprintf("%s(%s)", todo.Marker, todo.Author)
+		}
+		lines = append(lines, fmt.Sprintf("  %d:%s: %s", todo.Line, marker, todo.Text))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+//go:embed queries/comment_search.scm
+var commentSearchQuery string
+
+var todoPattern = regexp.MustCompile(`\b(TODO|FIXME|HACK|XXX)(?:\(([^)]*)\))?:?\s*(.*)`)
+
+// Generate a list of all comment nodes
+func parseComments(root *sitter.Node, contents []byte) ([]*sitter.Node, error) {
+	query, err := newQuery(commentSearchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	comments := []*sitter.Node{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, capture := range match.Captures {
+			comments = append(comments, capture.Node)
+		}
+	}
+
+	return comments, nil
+}
+
+func searchComments(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	comments, err := parseComments(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []ResultsFormatter{}
+	for _, comment := range comments {
+		if line := comment.Content(contents); strings.Contains(line, input.SearchTerms) {
+			matches = append(matches, Str{
+				Contents: line,
+				Line:     comment.StartPoint().Row,
+			})
+		}
+	}
+
+	return matches, nil
+}
+
+// Find TODO, FIXME, HACK and XXX markers in comments and group them by the module they
+// are in. Search terms are optional and filter the todos by their text or author.
+func searchTodos(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	comments, err := parseComments(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []TodoGroup{}
+	for _, comment := range comments {
+		found := todoPattern.FindStringSubmatch(comment.Content(contents))
+		if found == nil {
+			continue
+		}
+
+		todo := Todo{
+			Marker: found[1],
+			Author: found[2],
+			Text:   strings.TrimSpace(found[3]),
+			Line:   comment.StartPoint().Row,
+		}
+		if !strings.Contains(todo.Text, input.SearchTerms) && !strings.Contains(todo.Author, input.SearchTerms) {
+			continue
+		}
+
+		module := ""
+		if m := enclosingModule(comment, modules); m != nil {
+			module = m.Name
+		}
+
+		// keep groups in the order their modules are first seen
+		idx := -1
+		for i, group := range groups {
+			if group.Module == module {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			groups = append(groups, TodoGroup{Module: module})
+			idx = len(groups) - 1
+		}
+		groups[idx].Todos = append(groups[idx].Todos, todo)
+	}
+
+	matches := []ResultsFormatter{}
+	for _, group := range groups {
+		matches = append(matches, group)
+	}
+
+	return matches, nil
+}
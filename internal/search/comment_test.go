@@ -0,0 +1,80 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchComments(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/comments.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeComment,
+		SearchTerms: "card",
+	}
+
+	matches, err := searchComments(root, contents, input)
+	if err != nil {
+		t.Errorf("search comments failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Str{Contents: "# Charges the customer's card", Line: 2},
+		Str{Contents: "# XXX: remove once the card migration is done", Line: 13},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %v want %v", matches, expected)
+	}
+}
+
+func TestSearchTodos(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/comments.ex")
+	input := &SearchInput{
+		SearchType: SearchTypeTodo,
+	}
+
+	matches, err := searchTodos(root, contents, input)
+	if err != nil {
+		t.Errorf("search todos failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		TodoGroup{Module: "", Todos: []Todo{
+			{Marker: "HACK", Text: "work around a config loading bug", Line: 0},
+		}},
+		TodoGroup{Module: "TestApp.Billing", Todos: []Todo{
+			{Marker: "TODO", Author: "alice", Text: "retry failed charges", Line: 4},
+			{Marker: "XXX", Text: "remove once the card migration is done", Line: 13},
+		}},
+		TodoGroup{Module: "TestApp.Billing.Invoice", Todos: []Todo{
+			{Marker: "FIXME", Text: "totals are wrong for refunds", Line: 9},
+		}},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %+v want %+v", matches, expected)
+	}
+}
+
+func TestSearchTodosByAuthor(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/comments.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeTodo,
+		SearchTerms: "alice",
+	}
+
+	matches, err := searchTodos(root, contents, input)
+	if err != nil {
+		t.Errorf("search todos failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		TodoGroup{Module: "TestApp.Billing", Todos: []Todo{
+			{Marker: "TODO", Author: "alice", Text: "retry failed charges", Line: 4},
+		}},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %+v want %+v", matches, expected)
+	}
+}
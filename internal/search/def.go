@@ -0,0 +1,143 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+type FuncDef struct {
+	Module   string
+	Name     string
+	Arity    int
+	Defaults int    // The number of arguments with default values
+	Kind     string // def, defp, defmacro, defmacrop, defguard, defguardp or defdelegate
+	Line     uint32
+	Column   uint32
+	Contents string
+	node     *sitter.Node
+}
+
+func (f FuncDef) Format() string {
+	return fmt.Sprintf("%d:%s %s", f.Line, f.Kind, f.Contents)
+}
+
+// Signature returns the function in name/arity form
+func (f FuncDef) Signature() string {
+	return fmt.Sprintf("%s/%d", f.Name, f.Arity)
+}
+
+// HasArity checks if the function can be called with the given number of arguments,
+// taking default arguments into account.
+func (f FuncDef) HasArity(arity int) bool {
+	return arity >= f.Arity-f.Defaults && arity <= f.Arity
+}
+
+//go:embed queries/func_def.scm
+var funcDefQuery string
+
+// Generate a list of all function and macro definitions. Functions with multiple clauses
+// will have an entry per clause.
+func parseFuncDefs(root *sitter.Node, contents []byte, modules []Module) ([]FuncDef, error) {
+	query, err := newQuery(funcDefQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	defs := []FuncDef{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var node, keyword, head *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "identifier":
+				node = capture.Node
+			case "keyword":
+				keyword = capture.Node
+			case "func_name":
+				head = capture.Node
+			}
+		}
+
+		if node == nil || keyword == nil || head == nil {
+			continue
+		}
+
+		name, arity := funcHead(head, contents)
+		def := FuncDef{
+			Name:     name,
+			Arity:    arity,
+			Defaults: defaultArgs(head),
+			Kind:     keyword.Content(contents),
+			Line:     node.StartPoint().Row,
+			Column:   node.StartPoint().Column,
+			Contents: head.Content(contents),
+			node:     node,
+		}
+		if module := enclosingModule(node, modules); module != nil {
+			def.Module = module.Name
+		}
+
+		defs = append(defs, def)
+	}
+
+	return defs, nil
+}
+
+// get the name and arity from a function head like name(a, b) or name(a) when is_atom(a)
+func funcHead(head *sitter.Node, contents []byte) (string, int) {
+	if head.Type() == "binary_operator" {
+		head = head.ChildByFieldName("left")
+	}
+
+	if head.Type() == "identifier" {
+		return head.Content(contents), 0
+	}
+
+	return head.ChildByFieldName("target").Content(contents), callArity(head)
+}
+
+// count the arguments with default values like opts \\ []
+func defaultArgs(head *sitter.Node) int {
+	if head.Type() == "binary_operator" {
+		head = head.ChildByFieldName("left")
+	}
+
+	defaults := 0
+	for i := range int(head.NamedChildCount()) {
+		args := head.NamedChild(i)
+		if args.Type() != "arguments" {
+			continue
+		}
+
+		for j := range int(args.NamedChildCount()) {
+			arg := args.NamedChild(j)
+			if op := arg.ChildByFieldName("operator"); arg.Type() == "binary_operator" && op != nil && op.Type() == "\\\\" {
+				defaults++
+			}
+		}
+	}
+
+	return defaults
+}
+
+// count the arguments given to a call node
+func callArity(call *sitter.Node) int {
+	for i := range int(call.NamedChildCount()) {
+		if args := call.NamedChild(i); args.Type() == "arguments" {
+			return int(args.NamedChildCount())
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,301 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Location is a position in a file. Lines and columns are 1 based so they can be used
+// by editors.
+type Location struct {
+	Path     string `json:"path"`
+	Line     uint32 `json:"line"`
+	Column   uint32 `json:"column"`
+	Contents string `json:"contents"`
+}
+
+func (l Location) Format() string {
+	return fmt.Sprintf("%s:%d:%d %s", l.Path, l.Line, l.Column, l.Contents)
+}
+
+// Struct is a struct defined with defstruct or defexception
+type Struct struct {
+	Module string
+	Line   uint32
+	Column uint32
+	node   *sitter.Node
+}
+
+//go:embed queries/struct_def.scm
+var structDefQuery string
+
+// Generate a list of the structs defined in the file
+func parseStructs(root *sitter.Node, contents []byte, modules []Module) ([]Struct, error) {
+	query, err := newQuery(structDefQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	structs := []Struct{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		for _, capture := range match.Captures {
+			if query.CaptureNameForId(capture.Index) != "struct" {
+				continue
+			}
+
+			if module := enclosingModule(capture.Node, modules); module != nil {
+				structs = append(structs, Struct{
+					Module: module.Name,
+					Line:   capture.Node.StartPoint().Row,
+					Column: capture.Node.StartPoint().Column,
+					node:   capture.Node,
+				})
+			}
+		}
+	}
+
+	return structs, nil
+}
+
+// findDefinition finds where the symbol at the 1 based line and column of a file is
+// defined. Remote and local calls go to the function, aliases go to the module, structs
+// go to defstruct and attributes go to where the attribute is set.
+func findDefinition(files []*FileIndex, path string, line uint32, column uint32) ([]Location, error) {
+	root, contents, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return definitionAt(files, path, root, contents, line, column)
+}
+
+// definitionAt is findDefinition for a file that has already been parsed.
+func definitionAt(files []*FileIndex, path string, root *sitter.Node, contents []byte, line uint32, column uint32) ([]Location, error) {
+	var file *FileIndex
+	for _, f := range files {
+		if f.Path == path {
+			file = f
+		}
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s is not an elixir file in the project", path)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	if line == 0 || int(line) > len(lines) {
+		return nil, fmt.Errorf("line %d is out of range, %s has %d lines", line, filepath.Base(path), len(lines))
+	}
+	if column == 0 || int(column) > len(lines[line-1])+1 {
+		return nil, fmt.Errorf("column %d is out of range, line %d has %d columns", column, line, len(lines[line-1]))
+	}
+
+	point := sitter.Point{Row: line - 1, Column: column - 1}
+	node := root.NamedDescendantForPointRange(point, point)
+	if node == nil {
+		return nil, fmt.Errorf("nothing found at %d:%d", line, column)
+	}
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	// @name selects the attribute name
+	if node.Type() == "unary_operator" && node.ChildByFieldName("operator").Type() == "@" {
+		node = node.ChildByFieldName("operand")
+		if node.Type() == "call" {
+			node = node.ChildByFieldName("target")
+		}
+	}
+
+	locations := []Location{}
+	switch {
+	case node.Type() == "alias":
+		module := findFullModulePath(node.Content(contents), file.Aliases)
+
+		// %Struct{} goes to the defstruct rather than the module
+		if parent := node.Parent(); parent != nil && parent.Type() == "struct" {
+			locations = structLocations(files, module)
+		}
+
+		if len(locations) == 0 {
+			locations = moduleLocations(files, module)
+		}
+	case node.Type() == "identifier" && isAttributeName(node, contents):
+		locations = attributeLocations(node, contents, path, modules)
+	default:
+		locations = refLocations(files, file, node)
+	}
+
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("no definition found for %s at %d:%d", describeNode(node, contents), line, column)
+	}
+
+	return locations, nil
+}
+
+// describeNode names the node under the cursor in errors by its code, or by its type when
+// the code spans several lines like a whole defmodule
+func describeNode(node *sitter.Node, contents []byte) string {
+	code := node.Content(contents)
+	if strings.Contains(code, "\n") {
+		return node.Type()
+	}
+
+	return code
+}
+
+// calls and captures are found by matching the references at the position of the node or
+// one of its parents
+func refLocations(files []*FileIndex, file *FileIndex, node *sitter.Node) []Location {
+	if ref, ok := refAtNode(file, node); ok {
+		return functionLocations(files, ref)
+	}
+
+	return []Location{}
+}
+
+// check if an identifier is the name of a module attribute like @timeout
+func isAttributeName(node *sitter.Node, contents []byte) bool {
+	call := node.Parent()
+	if call != nil && call.Type() == "call" && call.ChildByFieldName("target").Equal(node) {
+		node = call
+	}
+
+	parent := node.Parent()
+	return parent != nil && parent.Type() == "unary_operator" && parent.ChildByFieldName("operator").Type() == "@"
+}
+
+func moduleLocations(files []*FileIndex, module string) []Location {
+	locations := []Location{}
+	for _, file := range files {
+		for _, m := range file.Modules {
+			if m.Name == module {
+				locations = append(locations, Location{
+					Path:     file.Path,
+					Line:     m.Line + 1,
+					Column:   m.Column + 1,
+					Contents: "defmodule " + m.Name,
+				})
+			}
+		}
+	}
+
+	return locations
+}
+
+func structLocations(files []*FileIndex, module string) []Location {
+	locations := []Location{}
+	for _, file := range files {
+		for _, s := range file.Structs {
+			if s.Module == module {
+				locations = append(locations, Location{
+					Path:     file.Path,
+					Line:     s.Line + 1,
+					Column:   s.Column + 1,
+					Contents: "defstruct " + s.Module,
+				})
+			}
+		}
+	}
+
+	return locations
+}
+
+// find the first clause of the referenced function. If no function has a matching arity
+// every function with the same name is returned.
+func functionLocations(files []*FileIndex, ref Ref) []Location {
+	exact := []Location{}
+	named := []Location{}
+	seen := map[string]bool{}
+	for _, file := range files {
+		for _, def := range file.Defs {
+			if def.Module != ref.Module || def.Name != ref.Name || seen[def.Signature()] {
+				continue
+			}
+			seen[def.Signature()] = true
+
+			location := Location{
+				Path:     file.Path,
+				Line:     def.Line + 1,
+				Column:   def.Column + 1,
+				Contents: fmt.Sprintf("%s %s", def.Kind, def.Contents),
+			}
+
+			named = append(named, location)
+			if def.HasArity(ref.Arity) {
+				exact = append(exact, location)
+			}
+		}
+	}
+
+	if len(exact) > 0 {
+		return exact
+	}
+
+	return named
+}
+
+// attributes are set in the module they are used in, so only the current file needs to be
+// searched
+func attributeLocations(node *sitter.Node, contents []byte, path string, modules []Module) []Location {
+	module := enclosingModule(node, modules)
+	if module == nil {
+		return []Location{}
+	}
+
+	name := node.Content(contents)
+	for _, child := range moduleBody(*module) {
+		if attr, args := attribute(child, contents); attr == name && args != nil {
+			return []Location{{
+				Path:     path,
+				Line:     child.StartPoint().Row + 1,
+				Column:   child.StartPoint().Column + 1,
+				Contents: child.Content(contents),
+			}}
+		}
+	}
+
+	return []Location{}
+}
+
+// DefinitionOf prints where the symbol at the 1 based line and column of a file is defined.
+func DefinitionOf(dir string, path string, line uint32, column uint32) error {
+	files, err := indexProject(dir)
+	if err != nil {
+		return err
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	locations, err := findDefinition(files, path, line, column)
+	if err != nil {
+		return err
+	}
+
+	for _, location := range locations {
+		if relPath, err := filepath.Rel(dir, location.Path); err == nil {
+			location.Path = relPath
+		}
+		fmt.Println(location.Format())
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindDefinition(t *testing.T) {
+	files, err := indexProject("testdata")
+	if err != nil {
+		t.Errorf("index project failed: %v", err)
+	}
+
+	getUser := []Location{{Path: "testdata/users.ex", Line: 13, Column: 3, Contents: "def get_user!(id)"}}
+	users := []Location{{Path: "testdata/users.ex", Line: 1, Column: 1, Contents: "defmodule TestApp.Accounts.Users"}}
+
+	tests := []struct {
+		name     string
+		path     string
+		line     uint32
+		column   uint32
+		expected []Location
+	}{
+		{"remote call", "testdata/users_test.exs", 15, 20, getUser},
+		{"alias", "testdata/users_test.exs", 15, 14, users},
+		{"imported call", "testdata/accounts.ex", 17, 22, getUser},
+		{"capture", "testdata/accounts.ex", 6, 60, getUser},
+		{"struct", "testdata/users_test.exs", 39, 45, []Location{
+			{Path: "testdata/user.ex", Line: 3, Column: 3, Contents: "defstruct TestApp.Accounts.User"},
+		}},
+		{"attribute", "testdata/sigils.ex", 13, 50, []Location{
+			{Path: "testdata/sigils.ex", Line: 4, Column: 3, Contents: "@username_format ~r/^[a-z_]+$/iu"},
+		}},
+	}
+
+	for _, test := range tests {
+		locations, err := findDefinition(files, test.path, test.line, test.column)
+		if err != nil {
+			t.Errorf("%s: find definition failed: %v", test.name, err)
+		}
+
+		if !reflect.DeepEqual(locations, test.expected) {
+			t.Errorf("%s: got %+v want %+v", test.name, locations, test.expected)
+		}
+	}
+}
+
+func TestFindDefinitionMissing(t *testing.T) {
+	files, err := indexProject("testdata")
+	if err != nil {
+		t.Errorf("index project failed: %v", err)
+	}
+
+	// Repo isn't defined in the project
+	if _, err := findDefinition(files, "testdata/users.ex", 13, 28); err == nil {
+		t.Errorf("expected an error for a function outside of the project")
+	}
+}
+
+func TestFindDefinitionOutOfRange(t *testing.T) {
+	files, err := indexProject("testdata")
+	if err != nil {
+		t.Errorf("index project failed: %v", err)
+	}
+
+	tests := map[string][2]uint32{
+		"line 999 is out of range, users.ex has 49 lines":   {999, 1},
+		"column 200 is out of range, line 1 has 35 columns": {1, 200},
+		"no definition found for do_block at 5:1":           {5, 1},
+		"no definition found for defmodule at 1:1":          {1, 1},
+	}
+
+	for expected, position := range tests {
+		_, err := findDefinition(files, "testdata/users.ex", position[0], position[1])
+		if err == nil || err.Error() != expected {
+			t.Errorf("%v: got %v want %s", position, err, expected)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// ModuleDep is a module depending on another module through an alias, use, require or a
+// struct. Calls and imports are kept in Refs and Imports.
+type ModuleDep struct {
+	Module string // The module with the dependency
+	Dep    string // The module depended on
+	Via    string // alias, use, require or struct
+	Line   uint32
+}
+
+// Generate a list of the modules each module depends on through aliases, use, require and
+// structs.
+func parseModuleDeps(root *sitter.Node, contents []byte, modules []Module, aliases []Alias) ([]ModuleDep, error) {
+	query, err := newQuery(aliasRefQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	deps := []ModuleDep{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, capture := range match.Captures {
+			node := capture.Node
+			module := enclosingModule(node, modules)
+			if module == nil {
+				continue
+			}
+
+			dep := ModuleDep{Module: module.Name, Line: node.StartPoint().Row}
+			name := node.Content(contents)
+			parent := node.Parent()
+
+			switch aliasContext(node, contents) {
+			case "alias":
+				dep.Via = "alias"
+				dep.Dep = name
+			case "alias_prefix":
+				for _, child := range namedChildren(node.NextNamedSibling()) {
+					deps = append(deps, ModuleDep{Module: module.Name, Dep: name + "." + child.Content(contents), Via: "alias", Line: dep.Line})
+				}
+				continue
+			case "":
+				switch {
+				case parent.Type() == "struct":
+					dep.Via = "struct"
+				case parent.Type() == "arguments" && parent.NamedChild(0).Equal(node):
+					dep.Via = callTarget(parent.Parent(), contents)
+				}
+				dep.Dep = findFullModulePath(name, aliases)
+			}
+
+			if dep.Via != "alias" && dep.Via != "struct" && dep.Via != "use" && dep.Via != "require" {
+				continue
+			}
+
+			deps = append(deps, dep)
+		}
+	}
+
+	return deps, nil
+}
+
+// DepsGraphInput holds the input for printing the module dependencies of a project.
+type DepsGraphInput struct {
+	Dir string
+
+	// Format is text, dot or json.
+	Format string
+}
+
+// depsGraph is the graph of dependencies between the modules of a project
+type depsGraph struct {
+	Modules []string   `json:"modules"`
+	Edges   []depsEdge `json:"edges"`
+	Cycles  [][]string `json:"cycles"` // Each cycle starts and ends with the same module
+}
+
+// depsEdge is a module depending on another. Compile time dependencies have to be
+// compiled first and cause the dependent module to be recompiled when they change.
+type depsEdge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind string   `json:"kind"` // compile or runtime
+	Via  []string `json:"via"`  // call, macro, alias, import, use, require or struct
+}
+
+// DepsGraph prints the dependencies between the modules of the project in dir and any
+// cycles between them.
+func DepsGraph(input *DepsGraphInput) error {
+	if !slices.Contains([]string{"text", "dot", "json"}, input.Format) {
+		return fmt.Errorf("unknown format %s, use text, dot or json", input.Format)
+	}
+
+	files, err := indexProject(input.Dir)
+	if err != nil {
+		return err
+	}
+
+	graph := buildDepsGraph(files)
+
+	switch input.Format {
+	case "text":
+		fmt.Print(graph.text())
+	case "dot":
+		fmt.Print(graph.dot())
+	case "json":
+		out, err := marshalJSON(graph)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	}
+
+	return nil
+}
+
+// buildDepsGraph builds the graph of dependencies between modules defined in the project.
+// use, require, import, calls to macros and calls made in a module's body outside of
+// functions are compile time dependencies, everything else is a runtime dependency.
+func buildDepsGraph(files []*FileIndex) depsGraph {
+	modules := map[string]bool{}
+	macros := map[string][]FuncDef{}
+	for _, file := range files {
+		for _, module := range file.Modules {
+			modules[module.Name] = true
+		}
+
+		for _, def := range file.Defs {
+			if def.Kind == "defmacro" || def.Kind == "defmacrop" {
+				macros[def.Module+"."+def.Name] = append(macros[def.Module+"."+def.Name], def)
+			}
+		}
+	}
+
+	edges := map[[2]string]*depsEdge{}
+	addEdge := func(from string, to string, via string, compile bool) {
+		if from == to || !modules[from] || !modules[to] {
+			return
+		}
+
+		edge, ok := edges[[2]string{from, to}]
+		if !ok {
+			edge = &depsEdge{From: from, To: to, Kind: "runtime", Via: []string{}}
+			edges[[2]string{from, to}] = edge
+		}
+
+		if compile {
+			edge.Kind = "compile"
+		}
+		if !slices.Contains(edge.Via, via) {
+			edge.Via = append(edge.Via, via)
+			sort.Strings(edge.Via)
+		}
+	}
+
+	for _, file := range files {
+		for _, ref := range file.Refs {
+			if ref.Module == "" {
+				continue
+			}
+
+			from := callerModule(ref.Caller)
+			inBody := from == ref.Caller
+
+			isMacro := slices.ContainsFunc(macros[ref.Module+"."+ref.Name], func(def FuncDef) bool { return def.HasArity(ref.Arity) })
+			if isMacro {
+				addEdge(from, ref.Module, "macro", true)
+			} else {
+				addEdge(from, ref.Module, "call", inBody)
+			}
+		}
+
+		for _, imp := range file.Imports {
+			addEdge(imp.Module, imp.Imported, "import", true)
+		}
+
+		for _, dep := range file.Deps {
+			addEdge(dep.Module, dep.Dep, dep.Via, dep.Via == "use" || dep.Via == "require")
+		}
+	}
+
+	graph := depsGraph{Modules: []string{}, Edges: []depsEdge{}}
+	for module := range modules {
+		graph.Modules = append(graph.Modules, module)
+	}
+	sort.Strings(graph.Modules)
+
+	for _, edge := range edges {
+		graph.Edges = append(graph.Edges, *edge)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+
+	graph.Cycles = graph.findCycles()
+	return graph
+}
+
+// findCycles finds the groups of modules that depend on each other, using Tarjan's
+// strongly connected components algorithm, and returns the shortest cycle through the
+// first module of each group.
+func (g depsGraph) findCycles() [][]string {
+	deps := map[string][]string{}
+	for _, edge := range g.Edges {
+		deps[edge.From] = append(deps[edge.From], edge.To)
+	}
+
+	index := map[string]int{}
+	lowLink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	components := [][]string{}
+
+	var connect func(module string)
+	connect = func(module string) {
+		index[module] = len(index)
+		lowLink[module] = index[module]
+		stack = append(stack, module)
+		onStack[module] = true
+
+		for _, dep := range deps[module] {
+			if _, ok := index[dep]; !ok {
+				connect(dep)
+				lowLink[module] = min(lowLink[module], lowLink[dep])
+			} else if onStack[dep] {
+				lowLink[module] = min(lowLink[module], index[dep])
+			}
+		}
+
+		if lowLink[module] != index[module] {
+			return
+		}
+
+		component := []string{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == module {
+				break
+			}
+		}
+
+		if len(component) > 1 {
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, module := range g.Modules {
+		if _, ok := index[module]; !ok {
+			connect(module)
+		}
+	}
+
+	sort.Slice(components, func(i, j int) bool { return components[i][0] < components[j][0] })
+
+	cycles := [][]string{}
+	for _, component := range components {
+		cycles = append(cycles, shortestCycle(component, deps))
+	}
+
+	return cycles
+}
+
+// shortestCycle finds the shortest path from the first module of a component back to
+// itself with a breadth first search.
+func shortestCycle(component []string, deps map[string][]string) []string {
+	start := component[0]
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		module := queue[0]
+		queue = queue[1:]
+
+		for _, dep := range deps[module] {
+			if !slices.Contains(component, dep) {
+				continue
+			}
+
+			if dep == start {
+				cycle := []string{start}
+				for m := module; m != start; m = prev[m] {
+					cycle = append(cycle, m)
+				}
+				cycle = append(cycle, start)
+				slices.Reverse(cycle)
+				return cycle
+			}
+
+			if _, ok := prev[dep]; !ok {
+				prev[dep] = module
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	return component
+}
+
+// text lists the dependencies of each module followed by any cycles
+func (g depsGraph) text() string {
+	out := &strings.Builder{}
+	from := ""
+	for _, edge := range g.Edges {
+		if edge.From != from {
+			if from != "" {
+				out.WriteString("\n")
+			}
+			from = edge.From
+			fmt.Fprintln(out, from)
+		}
+
+		fmt.Fprintf(out, "  -> %s (%s: %s)\n", edge.To, edge.Kind, strings.Join(edge.Via, ", "))
+	}
+
+	if len(g.Cycles) > 0 {
+		out.WriteString("\nCycles:\n")
+		for _, cycle := range g.Cycles {
+			fmt.Fprintf(out, "  %s\n", strings.Join(cycle, " -> "))
+		}
+	}
+
+	return out.String()
+}
+
+// dot formats the graph for Graphviz, labelling compile time dependencies
+func (g depsGraph) dot() string {
+	out := &strings.Builder{}
+	out.WriteString("digraph deps {\n")
+	for _, module := range g.Modules {
+		fmt.Fprintf(out, "  %q;\n", module)
+	}
+	for _, edge := range g.Edges {
+		if edge.Kind == "compile" {
+			fmt.Fprintf(out, "  %q -> %q [label=\"(compile)\"];\n", edge.From, edge.To)
+		} else {
+			fmt.Fprintf(out, "  %q -> %q;\n", edge.From, edge.To)
+		}
+	}
+	out.WriteString("}\n")
+
+	return out.String()
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var depsProject = map[string]string{
+	"macros.ex": `defmodule App.Macros do
+  defmacro trace(expr), do: expr
+end
+`,
+	"schema.ex": `defmodule App.Schema do
+  defstruct [:id]
+
+  def load(id), do: App.Repo.get(id)
+end
+`,
+	"repo.ex": `defmodule App.Repo do
+  alias App.{Schema, Macros}
+  require Macros
+
+  def get(id) do
+    Macros.trace(%Schema{id: id})
+  end
+end
+`,
+	"web.ex": `defmodule App.Web do
+  use App.Macros
+  import App.Schema
+
+  @schema App.Schema.load(1)
+
+  def show(id), do: load(id)
+end
+`,
+}
+
+func TestParseModuleDeps(t *testing.T) {
+	root, contents := readTestFile(t)
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		t.Fatalf("parse modules failed: %v", err)
+	}
+
+	aliases, err := parseAliases(root, contents)
+	if err != nil {
+		t.Fatalf("parse aliases failed: %v", err)
+	}
+
+	deps, err := parseModuleDeps(root, contents, modules, aliases)
+	if err != nil {
+		t.Fatalf("parse module deps failed: %v", err)
+	}
+
+	expected := []ModuleDep{
+		{Module: "TestApp.Accounts.Users", Dep: "TestApp.Repo", Via: "alias", Line: 5},
+		{Module: "TestApp.Accounts.Users", Dep: "TestApp.Accounts.User", Via: "alias", Line: 6},
+		{Module: "TestApp.Accounts.Users", Dep: "TestApp.Accounts.Admin", Via: "alias", Line: 6},
+		{Module: "TestApp.Accounts.Users", Dep: "TestApp.Result", Via: "alias", Line: 7},
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("got %+v want %+v", deps, expected)
+	}
+}
+
+func TestBuildDepsGraph(t *testing.T) {
+	files, err := indexProject(writeProjectFiles(t, depsProject))
+	if err != nil {
+		t.Fatalf("index project failed: %v", err)
+	}
+
+	graph := buildDepsGraph(files)
+
+	expected := []depsEdge{
+		{From: "App.Repo", To: "App.Macros", Kind: "compile", Via: []string{"alias", "macro", "require"}},
+		{From: "App.Repo", To: "App.Schema", Kind: "runtime", Via: []string{"alias", "struct"}},
+		{From: "App.Schema", To: "App.Repo", Kind: "runtime", Via: []string{"call"}},
+		{From: "App.Web", To: "App.Macros", Kind: "compile", Via: []string{"use"}},
+		{From: "App.Web", To: "App.Schema", Kind: "compile", Via: []string{"call", "import"}},
+	}
+	if !reflect.DeepEqual(graph.Edges, expected) {
+		t.Errorf("got %+v want %+v", graph.Edges, expected)
+	}
+
+	cycles := [][]string{{"App.Repo", "App.Schema", "App.Repo"}}
+	if !reflect.DeepEqual(graph.Cycles, cycles) {
+		t.Errorf("got cycles %v want %v", graph.Cycles, cycles)
+	}
+}
+
+func TestDepsGraphCycles(t *testing.T) {
+	graph := depsGraph{
+		Modules: []string{"A", "B", "C", "D"},
+		Edges: []depsEdge{
+			{From: "A", To: "B"},
+			{From: "B", To: "C"},
+			{From: "B", To: "D"},
+			{From: "C", To: "A"},
+			{From: "D", To: "B"},
+		},
+	}
+
+	// one group of modules, with the shortest way back to its first module
+	cycles := [][]string{{"A", "B", "C", "A"}}
+	if got := graph.findCycles(); !reflect.DeepEqual(got, cycles) {
+		t.Errorf("got %v want %v", got, cycles)
+	}
+
+	graph.Cycles = cycles
+	if text := graph.text(); !strings.Contains(text, "Cycles:\n  A -> B -> C -> A\n") {
+		t.Errorf("got\n%s\nwant the cycle listed", text)
+	}
+}
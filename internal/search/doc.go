@@ -0,0 +1,285 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// module attributes that hold documentation
+var docAttributes = []string{"doc", "moduledoc", "typedoc", "shortdoc"}
+
+// definitions that can be documented with @doc
+var docDefinitions = []string{"def", "defp", "defmacro", "defmacrop", "defguard", "defguardp", "defdelegate"}
+
+type Doc struct {
+	Kind     string // doc, moduledoc, typedoc or shortdoc
+	Owner    string // The module, Module.function/arity or Module.type/arity being documented
+	Line     uint32
+	Contents string
+}
+
+func (d Doc) Format() string {
+	if d.Owner == "" {
+		return fmt.Sprintf("%d:%s", d.Line, d.Contents)
+	}
+
+	return fmt.Sprintf("%d:[%s] %s", d.Line, d.Owner, d.Contents)
+}
+
+// Undocumented is a public function that has no @doc or is hidden with @doc false.
+type Undocumented struct {
+	Module   string
+	Def      FuncDef
+	DocFalse bool
+}
+
+func (u Undocumented) Format() string {
+	format := fmt.Sprintf("%d:%s.%s", u.Def.Line, u.Module, u.Def.Signature())
+	if u.DocFalse {
+		format += " (@doc false)"
+	}
+
+	return format
+}
+
+// docAttr is a documentation attribute and the value given to it, which is either a string,
+// a sigil, false or metadata keywords.
+type docAttr struct {
+	Kind  string
+	Owner string
+	node  *sitter.Node
+	value *sitter.Node
+}
+
+//go:embed queries/doc_search.scm
+var docSearchQuery string
+
+// Generate a list of all documentation attributes along with what they document.
+func parseDocAttrs(root *sitter.Node, contents []byte, modules []Module) ([]docAttr, error) {
+	query, err := newQuery(docSearchQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	attrs := []docAttr{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var attr, keyword, value *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "attribute":
+				attr = capture.Node
+			case "keyword":
+				keyword = capture.Node
+			case "doc":
+				value = capture.Node
+			}
+		}
+
+		if attr == nil || keyword == nil || value == nil {
+			continue
+		}
+
+		kind := keyword.Content(contents)
+		attrs = append(attrs, docAttr{
+			Kind:  kind,
+			Owner: docOwner(attr, kind, contents, modules),
+			node:  attr,
+			value: value,
+		})
+	}
+
+	return attrs, nil
+}
+
+// find what a documentation attribute belongs to. Module docs belong to the enclosing
+// module, function and type docs belong to the next definition after them.
+func docOwner(attr *sitter.Node, kind string, contents []byte, modules []Module) string {
+	moduleName := ""
+	if module := enclosingModule(attr, modules); module != nil {
+		moduleName = module.Name
+	}
+
+	if kind == "moduledoc" || kind == "shortdoc" {
+		return moduleName
+	}
+
+	// skip over other attributes like @spec and @impl, and comments
+	for sibling := attr.NextNamedSibling(); sibling != nil; sibling = sibling.NextNamedSibling() {
+		if sibling.Type() == "comment" {
+			continue
+		}
+
+		call := sibling
+		if sibling.Type() == "unary_operator" {
+			call = sibling.ChildByFieldName("operand")
+		}
+
+		target := call.ChildByFieldName("target")
+		if call.Type() != "call" || target == nil {
+			return ""
+		}
+
+		// types are documented by @typedoc, everything defined with def* by @doc
+		name := target.Content(contents)
+		isType := name == "type" || name == "opaque" || name == "typep"
+		isDef := slices.Contains(docDefinitions, name) && sibling.Type() == "call"
+		if (kind == "typedoc" && isType) || (kind == "doc" && isDef) {
+			head := call.NamedChild(1)
+			if head == nil || head.Type() != "arguments" || head.NamedChildCount() == 0 {
+				return ""
+			}
+
+			// type heads look like t :: term
+			head = head.NamedChild(0)
+			if isType && head.Type() == "binary_operator" {
+				head = head.ChildByFieldName("left")
+			}
+
+			if head.Type() != "call" && head.Type() != "identifier" && head.Type() != "binary_operator" {
+				return ""
+			}
+
+			fnName, arity := funcHead(head, contents)
+			return fmt.Sprintf("%s.%s/%d", moduleName, fnName, arity)
+		}
+
+		if sibling.Type() != "unary_operator" {
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func searchDoc(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := parseDocAttrs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []ResultsFormatter{}
+	for _, attr := range attrs {
+		// only search docs with text, not @doc false or metadata like @doc since: "1.0"
+		if attr.value.Type() != "string" && attr.value.Type() != "sigil" {
+			continue
+		}
+
+		lines := strings.Split(attr.value.Content(contents), "\n")
+		for i, line := range lines {
+			if strings.Contains(line, input.SearchTerms) {
+				matches = append(matches, Doc{
+					Kind:     attr.Kind,
+					Owner:    attr.Owner,
+					Contents: line,
+					Line:     attr.value.StartPoint().Row + uint32(i),
+				})
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// check if the node is nested inside of a call like defimpl
+func insideCall(node *sitter.Node, contents []byte, name string) bool {
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		if target := parent.ChildByFieldName("target"); parent.Type() == "call" && target != nil && target.Content(contents) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Find public functions that are missing documentation, skipping modules hidden with
+// @moduledoc false and callbacks marked with @impl. Search terms are optional and filter
+// the functions by name.
+func searchUndocumented(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	aliases, err := parseAliases(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := parseFuncDefs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := parseDocAttrs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	impls, err := parseImplAttrs(root, contents, defs, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	documented := map[string]bool{}
+	hidden := map[string]bool{}
+	for _, attr := range attrs {
+		isFalse := attr.value.Type() == "boolean" && attr.value.Content(contents) == "false"
+		if attr.Kind == "moduledoc" && isFalse {
+			hidden[attr.Owner] = true
+		} else if attr.Kind == "doc" && attr.Owner != "" {
+			switch {
+			case isFalse:
+				documented[attr.Owner] = false
+			case attr.value.Type() == "string" || attr.value.Type() == "sigil":
+				documented[attr.Owner] = true
+			}
+		}
+	}
+
+	matches := []ResultsFormatter{}
+	seen := map[string]bool{}
+	for _, def := range defs {
+		owner := fmt.Sprintf("%s.%s", def.Module, def.Signature())
+		isImpl := slices.ContainsFunc(impls, func(impl implAttr) bool {
+			return impl.Module == def.Module && impl.Def.Signature() == def.Signature()
+		})
+
+		// only report the first clause of public functions. Protocol implementations are
+		// documented by the protocol.
+		if (def.Kind != "def" && def.Kind != "defmacro") || hidden[def.Module] || isImpl || seen[owner] ||
+			insideCall(def.node, contents, "defimpl") {
+			continue
+		}
+		seen[owner] = true
+
+		isDocumented, hasDoc := documented[owner]
+		if !isDocumented && strings.Contains(def.Name, input.SearchTerms) {
+			matches = append(matches, Undocumented{
+				Module:   def.Module,
+				Def:      def,
+				DocFalse: hasDoc,
+			})
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchDoc(t *testing.T) {
+	root, contents := readTestFile(t)
+	input := &SearchInput{
+		SearchType:  SearchTypeDoc,
+		SearchTerms: "user",
+	}
+
+	matches, err := searchDoc(root, contents, input)
+	if err != nil {
+		t.Errorf("search doc failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Doc{Kind: "doc", Owner: "TestApp.Accounts.Users.get_user!/1", Contents: "  Get a user by id", Line: 10},
+		Doc{Kind: "doc", Owner: "TestApp.Accounts.Users.hello_message/1", Contents: "  Returns a hello message for the user as a string", Line: 26},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %v want %v", matches, expected)
+	}
+}
+
+func TestSearchDocKinds(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/docs.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeDoc,
+		SearchTerms: "rofile",
+	}
+
+	matches, err := searchDoc(root, contents, input)
+	if err != nil {
+		t.Errorf("search doc failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Doc{Kind: "moduledoc", Owner: "TestApp.Accounts.Profile", Contents: "  Profiles for #{user} accounts", Line: 2},
+		Doc{Kind: "typedoc", Owner: "TestApp.Accounts.Profile.t/0", Contents: "\"A user profile\"", Line: 5},
+		Doc{Kind: "doc", Owner: "TestApp.Accounts.Profile.new/1", Contents: "  Builds a profile for a user", Line: 12},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %v want %v", matches, expected)
+	}
+}
+
+func TestSearchUndocumented(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/docs.ex")
+	input := &SearchInput{
+		SearchType:   SearchTypeDoc,
+		Undocumented: true,
+	}
+
+	matches, err := searchUndocumented(root, contents, input)
+	if err != nil {
+		t.Errorf("search undocumented failed: %v", err)
+	}
+
+	output := []string{}
+	for _, match := range matches {
+		output = append(output, match.Format())
+	}
+
+	expected := []string{
+		"18:TestApp.Accounts.Profile.internal_bio/1 (@doc false)",
+		"20:TestApp.Accounts.Profile.display_bio/1",
+		"21:TestApp.Accounts.Profile.display_bio/2",
+	}
+
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %v want %v", output, expected)
+	}
+}
@@ -0,0 +1,159 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/elixir"
+)
+
+// Doctest is an iex> example from a @doc or @moduledoc along with its expected result.
+type Doctest struct {
+	Owner      string // The module or Module.function/arity the doc belongs to
+	Line       uint32 // The file row of the first iex> line
+	Expression string // The example code without the iex> and ...> prompts
+	Expected   string // The expected result, empty when the example has none
+}
+
+func (d Doctest) Format() string {
+	lines := []string{}
+	for i, line := range strings.Split(d.Expression, "\n") {
+		prompt := "iex>"
+		if i > 0 {
+			prompt = "...>"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", prompt, line))
+	}
+
+	for _, line := range strings.Split(d.Expected, "\n") {
+		if line != "" {
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	return fmt.Sprintf("%d:[%s] %s", d.Line, d.Owner, strings.Join(lines, "\n"))
+}
+
+// Generate a list of all doctests in @doc and @moduledoc strings. Consecutive iex> lines
+// are separate examples, and ...> lines continue the previous example.
+func parseDoctests(root *sitter.Node, contents []byte, modules []Module) ([]Doctest, error) {
+	attrs, err := parseDocAttrs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	doctests := []Doctest{}
+	for _, attr := range attrs {
+		if (attr.Kind != "doc" && attr.Kind != "moduledoc") || (attr.value.Type() != "string" && attr.value.Type() != "sigil") {
+			continue
+		}
+
+		var current *Doctest
+		var expected []string
+		finish := func() {
+			if current != nil {
+				current.Expected = strings.Join(expected, "\n")
+				doctests = append(doctests, *current)
+			}
+			current, expected = nil, nil
+		}
+
+		for i, line := range strings.Split(attr.value.Content(contents), "\n") {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(trimmed, "iex>") || strings.HasPrefix(trimmed, "iex("):
+				finish()
+				current = &Doctest{
+					Owner:      attr.Owner,
+					Line:       attr.value.StartPoint().Row + uint32(i),
+					Expression: stripPrompt(trimmed),
+				}
+			case strings.HasPrefix(trimmed, "...>") || strings.HasPrefix(trimmed, "...("):
+				if current != nil && len(expected) == 0 {
+					current.Expression += "\n" + stripPrompt(trimmed)
+				}
+			case trimmed == "" || trimmed == `"""`:
+				finish()
+			case current != nil:
+				expected = append(expected, trimmed)
+			}
+		}
+		finish()
+	}
+
+	return doctests, nil
+}
+
+// remove iex>, iex(1)> and ...> prompts from the start of a doctest line
+func stripPrompt(line string) string {
+	if idx := strings.Index(line, ">"); idx >= 0 {
+		return strings.TrimSpace(line[idx+1:])
+	}
+
+	return line
+}
+
+// Parse the code in each doctest and find the remote function calls in it. The lines
+// of the calls are mapped back to the lines in the file.
+func parseDoctestCalls(root *sitter.Node, contents []byte, aliases []Alias) ([]FnCall, error) {
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	doctests, err := parseDoctests(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := []FnCall{}
+	for _, doctest := range doctests {
+		code := []byte(doctest.Expression)
+		codeRoot, err := sitter.ParseCtx(context.Background(), code, elixir.GetLanguage())
+		if err != nil {
+			return nil, err
+		}
+
+		codeCalls, err := parseRemoteCalls(codeRoot, code, aliases)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, call := range codeCalls {
+			call.Line += doctest.Line
+			calls = append(calls, call)
+		}
+	}
+
+	return calls, nil
+}
+
+func searchDoctests(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	doctests, err := parseDoctests(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []ResultsFormatter{}
+	for _, doctest := range doctests {
+		if strings.Contains(doctest.Expression, input.SearchTerms) || strings.Contains(doctest.Expected, input.SearchTerms) {
+			matches = append(matches, doctest)
+		}
+	}
+
+	return matches, nil
+}
+
+// keep function calls found in code and in doctests in file order
+func sortFnCalls(calls []FnCall) {
+	slices.SortStableFunc(calls, func(a, b FnCall) int { return cmp.Compare(a.Line, b.Line) })
+}
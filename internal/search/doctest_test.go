@@ -0,0 +1,91 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchDoctests(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/doctests.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeDoctest,
+		SearchTerms: "User",
+	}
+
+	matches, err := searchDoctests(root, contents, input)
+	if err != nil {
+		t.Errorf("search doctests failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Doctest{
+			Owner:      "TestApp.Accounts.Lookup.find/2",
+			Line:       15,
+			Expression: "user = Users.get_user!(1)",
+		},
+		Doctest{
+			Owner:      "TestApp.Accounts.Lookup.find/2",
+			Line:       19,
+			Expression: "Lookup.find(1,\npreload: true\n)",
+			Expected:   "%User{id: 1}",
+		},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %+v want %+v", matches, expected)
+	}
+}
+
+func TestParseDoctests(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/doctests.ex")
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		t.Errorf("parse modules failed: %v", err)
+	}
+
+	doctests, err := parseDoctests(root, contents, modules)
+	if err != nil {
+		t.Errorf("parse doctests failed: %v", err)
+	}
+
+	if len(doctests) != 4 {
+		t.Fatalf("got %d doctests want 4", len(doctests))
+	}
+
+	expected := Doctest{
+		Owner:      "TestApp.Accounts.Lookup",
+		Line:       4,
+		Expression: "TestApp.Accounts.Lookup.ready?()",
+		Expected:   "true",
+	}
+
+	if !reflect.DeepEqual(doctests[0], expected) {
+		t.Errorf("got %+v want %+v", doctests[0], expected)
+	}
+}
+
+func TestSearchFnCallsDoctests(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/doctests.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeFnCall,
+		SearchTerms: "Users.get_user!",
+		Doctests:    true,
+	}
+
+	fnCalls, _ := searchFnCalls(root, contents, input)
+	expected := []ResultsFormatter{
+		FnCall{ModulePath: "TestApp.Accounts.Users", Name: "get_user!", Contents: "Users.get_user!(1)", Line: 15},
+		FnCall{ModulePath: "TestApp.Accounts.Users", Name: "get_user!", Contents: "Users.get_user!(id)", Line: 24},
+	}
+
+	if !reflect.DeepEqual(fnCalls, expected) {
+		t.Errorf("got %v want %v", fnCalls, expected)
+	}
+
+	// doctests are only searched when asked for
+	input.Doctests = false
+	fnCalls, _ = searchFnCalls(root, contents, input)
+	if len(fnCalls) != 1 {
+		t.Errorf("got %v want only the call in code", fnCalls)
+	}
+}
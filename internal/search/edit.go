@@ -0,0 +1,338 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes in diffs
+const diffContext = 3
+
+// textEdit replaces the bytes from Start up to End of a file with Text
+type textEdit struct {
+	Start uint32
+	End   uint32
+	Text  string
+}
+
+// applyEdits returns the contents with the edits made. Edits overlapping an earlier edit
+// are dropped.
+func applyEdits(contents []byte, edits []textEdit) []byte {
+	edits = slices.Clone(edits)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+
+	out := []byte{}
+	pos := uint32(0)
+	for _, edit := range edits {
+		if edit.Start < pos {
+			continue
+		}
+
+		out = append(out, contents[pos:edit.Start]...)
+		out = append(out, edit.Text...)
+		pos = edit.End
+	}
+
+	return append(out, contents[pos:]...)
+}
+
+// fileChange is a new version of a file, which may also be moving to a new path
+type fileChange struct {
+	Path    string // The path relative to the project directory
+	NewPath string // Only set when the file moves
+	Before  []byte
+	After   []byte
+}
+
+// diff returns the change as a unified diff
+func (c fileChange) diff() string {
+	newPath := c.Path
+	if c.NewPath != "" {
+		newPath = c.NewPath
+	}
+
+	return unifiedDiff(c.Path, newPath, c.Before, c.After)
+}
+
+// applyChanges writes the changes and lists the files that changed, or with dryRun prints
+// them as a unified diff without writing anything.
+func applyChanges(dir string, changes []fileChange, dryRun bool) error {
+	if dryRun {
+		for _, change := range changes {
+			fmt.Print(change.diff())
+		}
+		return nil
+	}
+
+	if err := writeChanges(dir, changes); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		if change.NewPath != "" {
+			fmt.Printf("%s -> %s\n", change.Path, change.NewPath)
+		} else {
+			fmt.Println(change.Path)
+		}
+	}
+
+	return nil
+}
+
+// writeChanges writes every change to disk relative to dir, moving files as needed. Every
+// file is written to a temporary file first and they are only renamed into place once all
+// of them have been written, so a failed write leaves the project untouched.
+func writeChanges(dir string, changes []fileChange) error {
+	tmps := []string{}
+	defer func() {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}()
+
+	for _, change := range changes {
+		info, err := os.Stat(filepath.Join(dir, change.Path))
+		if err != nil {
+			return err
+		}
+
+		newPath := filepath.Join(dir, change.Path)
+		if change.NewPath != "" {
+			newPath = filepath.Join(dir, change.NewPath)
+			if err := os.MkdirAll(filepath.Dir(newPath), 0o755); err != nil {
+				return err
+			}
+		}
+
+		tmp, err := writeTempFile(newPath, change.After, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		tmps = append(tmps, tmp)
+	}
+
+	written := []string{}
+	for i, change := range changes {
+		path := filepath.Join(dir, change.Path)
+		newPath := path
+		if change.NewPath != "" {
+			newPath = filepath.Join(dir, change.NewPath)
+		}
+
+		if err := os.Rename(tmps[i], newPath); err != nil {
+			return writtenError(written, err)
+		}
+
+		if newPath != path {
+			if err := os.Remove(path); err != nil {
+				return writtenError(append(written, change.NewPath), err)
+			}
+		}
+
+		written = append(written, change.Path)
+	}
+
+	return nil
+}
+
+// writtenError reports the files that were already written when writing the rest failed
+func writtenError(written []string, err error) error {
+	if len(written) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("%w, after already writing %s", err, strings.Join(written, ", "))
+}
+
+// writeTempFile writes the contents to a temporary file next to path, which can be renamed
+// over path so the file is never left half written.
+func writeTempFile(path string, contents []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// diffOp is a line kept (' '), removed ('-') or added ('+') by a diff
+type diffOp struct {
+	kind byte
+	line string // The line including its newline, if it has one
+}
+
+// unifiedDiff returns a unified diff between two versions of a file, or an empty string
+// when they are the same.
+func unifiedDiff(fromPath string, toPath string, before []byte, after []byte) string {
+	ops := diffLines(splitLines(before), splitLines(after))
+	if !slices.ContainsFunc(ops, func(op diffOp) bool { return op.kind != ' ' }) {
+		return ""
+	}
+
+	// the line numbers in each version of the file before each op
+	oldLines := make([]int, len(ops)+1)
+	newLines := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if op.kind != '+' {
+			oldLines[i+1]++
+		}
+		if op.kind != '-' {
+			newLines[i+1]++
+		}
+	}
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "--- a/%s\n+++ b/%s\n", fromPath, toPath)
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// a hunk runs until there are enough unchanged lines to separate it from the next
+		last := i
+		for j := i; j < len(ops) && j-last <= 2*diffContext+1; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+
+		start := max(i-diffContext, 0)
+		end := min(last+diffContext+1, len(ops))
+
+		fmt.Fprintf(out, "@@ -%s +%s @@\n",
+			hunkRange(oldLines[start], oldLines[end]-oldLines[start]),
+			hunkRange(newLines[start], newLines[end]-newLines[start]))
+
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the start and length of a hunk, where start is the number of lines
+// before it.
+func hunkRange(start int, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// splitLines splits contents after each newline
+func splitLines(contents []byte) []string {
+	lines := strings.SplitAfter(string(contents), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// diffLines finds the shortest edit script turning a into b using the Myers algorithm.
+func diffLines(a []string, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// keep the furthest x of every diagonal k for each number of edits d to walk back
+	// through once b has been reached
+	trace := [][]int{}
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v))
+
+		done := false
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+
+		if done {
+			break
+		}
+	}
+
+	ops := []diffOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{kind: ' ', line: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{kind: '+', line: b[y-1]})
+			} else {
+				ops = append(ops, diffOp{kind: '-', line: a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	slices.Reverse(ops)
+	return ops
+}
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyEdits(t *testing.T) {
+	contents := []byte("Repo.get!(User, id)")
+	edits := []textEdit{
+		{Start: 10, End: 14, Text: "Admin"},
+		{Start: 0, End: 19, Text: "ignored"},
+		{Start: 5, End: 9, Text: "fetch!"},
+	}
+
+	// the overlapping edit starts first, so the others are dropped
+	if got := string(applyEdits(contents, edits)); got != "ignored" {
+		t.Errorf("got %q want %q", got, "ignored")
+	}
+
+	if got := string(applyEdits(contents, []textEdit{edits[0], edits[2]})); got != "Repo.fetch!(Admin, id)" {
+		t.Errorf("got %q want %q", got, "Repo.fetch!(Admin, id)")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nm\nn"
+
+	expected := strings.Join([]string{
+		"--- a/old.ex",
+		"+++ b/new.ex",
+		"@@ -1,5 +1,5 @@",
+		" a",
+		"-b",
+		"+B",
+		" c",
+		" d",
+		" e",
+		"@@ -9,5 +9,5 @@",
+		" i",
+		" j",
+		" k",
+		"-l",
+		" m",
+		"+n",
+		`\ No newline at end of file`,
+		"",
+	}, "\n")
+
+	if got := unifiedDiff("old.ex", "new.ex", []byte(before), []byte(after)); got != expected {
+		t.Errorf("got\n%s\nwant\n%s", got, expected)
+	}
+
+	if got := unifiedDiff("old.ex", "old.ex", []byte(before), []byte(before)); got != "" {
+		t.Errorf("got\n%s\nwant no diff", got)
+	}
+
+	expected = "--- a/new.ex\n+++ b/new.ex\n@@ -0,0 +1,2 @@\n+a\n+b\n"
+	if got := unifiedDiff("new.ex", "new.ex", nil, []byte("a\nb\n")); got != expected {
+		t.Errorf("got\n%s\nwant\n%s", got, expected)
+	}
+}
+
+func TestUnifiedDiffHunkBoundary(t *testing.T) {
+	// changes separated by 2*diffContext unchanged lines share their context, so they're
+	// one hunk, one more unchanged line separates them
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+	after := "A\nb\nc\nd\ne\nf\ng\nH\ni\n"
+
+	expected := strings.Join([]string{
+		"--- a/old.ex",
+		"+++ b/old.ex",
+		"@@ -1,9 +1,9 @@",
+		"-a",
+		"+A",
+		" b",
+		" c",
+		" d",
+		" e",
+		" f",
+		" g",
+		"-h",
+		"+H",
+		" i",
+		"",
+	}, "\n")
+	if got := unifiedDiff("old.ex", "old.ex", []byte(before), []byte(after)); got != expected {
+		t.Errorf("got\n%s\nwant\n%s", got, expected)
+	}
+
+	after = "A\nb\nc\nd\ne\nf\ng\nh\nI\n"
+	if got := unifiedDiff("old.ex", "old.ex", []byte(before), []byte(after)); strings.Count(got, "@@ -") != 2 {
+		t.Errorf("got\n%s\nwant two hunks", got)
+	}
+}
+
+func TestWriteChanges(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "old.ex"), []byte("old"), 0o600); err != nil {
+		t.Fatalf("Unable to write file, %v", err)
+	}
+
+	changes := []fileChange{{Path: "old.ex", NewPath: filepath.Join("lib", "new.ex"), Before: []byte("old"), After: []byte("new")}}
+	if err := writeChanges(dir, changes); err != nil {
+		t.Fatalf("write changes failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "old.ex")); !os.IsNotExist(err) {
+		t.Errorf("old.ex should have been moved, got %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "lib", "new.ex"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("got %v %v want the file with its mode kept", info, err)
+	}
+
+	if contents := string(mustRead(t, filepath.Join(dir, "lib", "new.ex"))); contents != "new" {
+		t.Errorf("got %q want %q", contents, "new")
+	}
+}
+
+func TestWriteChangesFailure(t *testing.T) {
+	dir := writeProjectFiles(t, map[string]string{"a.ex": "a"})
+
+	// b.ex doesn't exist, so neither file is written
+	changes := []fileChange{
+		{Path: "a.ex", Before: []byte("a"), After: []byte("A")},
+		{Path: "b.ex", Before: []byte("b"), After: []byte("B")},
+	}
+	if err := writeChanges(dir, changes); err == nil {
+		t.Fatalf("expected an error writing b.ex")
+	}
+
+	if contents := string(mustRead(t, filepath.Join(dir, "a.ex"))); contents != "a" {
+		t.Errorf("got %q want a.ex left untouched", contents)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("got %v %v want the temporary files removed", entries, err)
+	}
+}
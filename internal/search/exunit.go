@@ -0,0 +1,363 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// TestCase is an ExUnit test along with the describe block, tags and setups that apply
+// to it.
+type TestCase struct {
+	Module   string   // The test module
+	Subject  string   // The module under test, from doctest or the test module name
+	Describe string   // The describe block the test is in
+	Name     string   // The test name
+	Tags     []string // Tags from @tag, @describetag and @moduletag
+	Setups   []string // Setups that run before the test
+	Line     uint32
+	Calls    []FnCall // Calls in the test body matching SearchInput.Calls
+	node     *sitter.Node
+}
+
+func (t TestCase) Format() string {
+	subject := t.Subject
+	if subject == "" {
+		subject = t.Module
+	}
+
+	line := fmt.Sprintf("%d:[%s] ", t.Line, subject)
+	if t.Describe != "" {
+		line += fmt.Sprintf("describe %q ", t.Describe)
+	}
+	line += fmt.Sprintf("test %q", t.Name)
+
+	if len(t.Tags) > 0 {
+		line += " tags: " + strings.Join(t.Tags, ", ")
+	}
+	if len(t.Setups) > 0 {
+		line += " setup: " + strings.Join(t.Setups, ", ")
+	}
+
+	lines := []string{line}
+	for _, call := range t.Calls {
+		lines = append(lines, fmt.Sprintf("  %s", call.Format()))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// testBlock is a test or describe call
+type testBlock struct {
+	Kind string
+	Name string
+	node *sitter.Node
+}
+
+// testSetup is a setup or setup_all call and the name reported for it
+type testSetup struct {
+	Names []string
+	node  *sitter.Node
+}
+
+//go:embed queries/exunit.scm
+var exunitQuery string
+
+//go:embed queries/exunit_setup.scm
+var exunitSetupQuery string
+
+// Generate a list of all ExUnit tests with the describe block, tags and setup for each.
+func parseTestCases(root *sitter.Node, contents []byte, modules []Module) ([]TestCase, error) {
+	blocks, err := parseTestBlocks(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	setups, err := parseTestSetups(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	tests := []TestCase{}
+	for _, block := range blocks {
+		if block.Kind != "test" {
+			continue
+		}
+
+		test := TestCase{
+			Name: block.Name,
+			Tags: precedingTags(block.node, contents, "tag"),
+			Line: block.node.StartPoint().Row,
+			node: block.node,
+		}
+
+		module := enclosingModule(block.node, modules)
+		if module != nil {
+			test.Module = module.Name
+			test.Subject = testSubject(*module, contents)
+			test.Tags = append(test.Tags, blockTags(module.node, contents, "moduletag")...)
+		}
+
+		var describe *testBlock
+		for i, block := range blocks {
+			if block.Kind == "describe" && containsNode(block.node, test.node) {
+				describe = &blocks[i]
+			}
+		}
+
+		if describe != nil {
+			test.Describe = describe.Name
+			test.Tags = append(test.Tags, blockTags(describe.node, contents, "describetag")...)
+		}
+
+		// module level setups run for every test, describe level setups only in the describe
+		for _, setup := range setups {
+			setupDescribe := ""
+			for _, block := range blocks {
+				if block.Kind == "describe" && containsNode(block.node, setup.node) {
+					setupDescribe = block.Name
+				}
+			}
+
+			sameModule := module != nil && enclosingModule(setup.node, modules) == module
+			if sameModule && (setupDescribe == "" || setupDescribe == test.Describe) {
+				test.Setups = append(test.Setups, setup.Names...)
+			}
+		}
+
+		tests = append(tests, test)
+	}
+
+	return tests, nil
+}
+
+func parseTestBlocks(root *sitter.Node, contents []byte) ([]testBlock, error) {
+	query, err := newQuery(exunitQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	blocks := []testBlock{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		block := testBlock{}
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "block":
+				block.node = capture.Node
+			case "keyword":
+				block.Kind = capture.Node.Content(contents)
+			case "name":
+				block.Name = strings.Trim(capture.Node.Content(contents), `"`)
+			}
+		}
+
+		if block.node != nil {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks, nil
+}
+
+func parseTestSetups(root *sitter.Node, contents []byte) ([]testSetup, error) {
+	query, err := newQuery(exunitSetupQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	setups := []testSetup{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		for _, capture := range match.Captures {
+			if query.CaptureNameForId(capture.Index) != "setup" {
+				continue
+			}
+
+			// setup :name and setup [:a, :b] are named after their functions, setup blocks
+			// after their line
+			setup := testSetup{node: capture.Node}
+			keyword := capture.Node.ChildByFieldName("target").Content(contents)
+			if args := capture.Node.NamedChild(1); args != nil && args.Type() == "arguments" {
+				setup.Names = atomNames(args, contents)
+			}
+			if len(setup.Names) == 0 {
+				setup.Names = []string{fmt.Sprintf("%s@%d", keyword, capture.Node.StartPoint().Row)}
+			}
+
+			setups = append(setups, setup)
+		}
+	}
+
+	return setups, nil
+}
+
+// get the names of atoms given directly or in a list, with keyword keys treated like
+// atoms so @tag slow: true becomes slow.
+func atomNames(node *sitter.Node, contents []byte) []string {
+	names := []string{}
+	for i := range int(node.NamedChildCount()) {
+		child := node.NamedChild(i)
+		switch child.Type() {
+		case "atom":
+			names = append(names, strings.TrimPrefix(child.Content(contents), ":"))
+		case "list", "keywords":
+			names = append(names, atomNames(child, contents)...)
+		case "pair":
+			key := strings.TrimSpace(child.ChildByFieldName("key").Content(contents))
+			names = append(names, strings.TrimSuffix(key, ":"))
+		}
+	}
+
+	return names
+}
+
+// find @tag attributes directly before a test
+func precedingTags(node *sitter.Node, contents []byte, attr string) []string {
+	tags := []string{}
+	for sibling := node.PrevNamedSibling(); sibling != nil; sibling = sibling.PrevNamedSibling() {
+		if sibling.Type() == "comment" {
+			continue
+		}
+
+		name, args := attribute(sibling, contents)
+		if name == "" {
+			break
+		}
+
+		if name == attr && args != nil {
+			tags = append(atomNames(args, contents), tags...)
+		}
+	}
+
+	return tags
+}
+
+// find attributes like @moduletag set directly inside of a block
+func blockTags(node *sitter.Node, contents []byte, attr string) []string {
+	tags := []string{}
+	for i := range int(node.NamedChildCount()) {
+		block := node.NamedChild(i)
+		if block.Type() != "do_block" {
+			continue
+		}
+
+		for j := range int(block.NamedChildCount()) {
+			if name, args := attribute(block.NamedChild(j), contents); name == attr && args != nil {
+				tags = append(tags, atomNames(args, contents)...)
+			}
+		}
+	}
+
+	return tags
+}
+
+// get the name and arguments of a module attribute like @tag :slow
+func attribute(node *sitter.Node, contents []byte) (string, *sitter.Node) {
+	if node.Type() != "unary_operator" {
+		return "", nil
+	}
+
+	operand := node.ChildByFieldName("operand")
+	if operand == nil || operand.Type() != "call" {
+		return "", nil
+	}
+
+	var args *sitter.Node
+	if child := operand.NamedChild(1); child != nil && child.Type() == "arguments" {
+		args = child
+	}
+
+	return operand.ChildByFieldName("target").Content(contents), args
+}
+
+// the module under test is the one given to doctest, or the test module without the
+// Test suffix
+func testSubject(module Module, contents []byte) string {
+	for _, node := range moduleBody(module) {
+		target := node.ChildByFieldName("target")
+		if node.Type() == "call" && target != nil && target.Content(contents) == "doctest" {
+			if args := node.NamedChild(1); args != nil && args.NamedChildCount() > 0 {
+				return args.NamedChild(0).Content(contents)
+			}
+		}
+	}
+
+	return strings.TrimSuffix(module.Name, "Test")
+}
+
+func searchTestCases(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	aliases, err := parseAliases(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	tests, err := parseTestCases(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []ResultsFormatter{}
+	for _, test := range tests {
+		name := test.Describe + " " + test.Name
+		if !strings.Contains(name, input.SearchTerms) {
+			continue
+		}
+
+		hasTags := true
+		for _, tag := range input.Tags {
+			hasTags = hasTags && slices.Contains(test.Tags, strings.TrimPrefix(tag, ":"))
+		}
+		if !hasTags {
+			continue
+		}
+
+		// only keep tests that make a matching call in their body
+		if input.Calls != "" {
+			calls, err := parseRemoteCalls(test.node, contents, aliases)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, call := range calls {
+				if strings.Contains(fmt.Sprintf("%s.%s", call.ModulePath, call.Name), input.Calls) {
+					test.Calls = append(test.Calls, call)
+				}
+			}
+
+			if len(test.Calls) == 0 {
+				continue
+			}
+		}
+
+		matches = append(matches, test)
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTestCases(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/users_test.exs")
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		t.Errorf("parse modules failed: %v", err)
+	}
+
+	tests, err := parseTestCases(root, contents, modules)
+	if err != nil {
+		t.Errorf("parse test cases failed: %v", err)
+	}
+
+	output := []string{}
+	for _, test := range tests {
+		output = append(output, test.Format())
+	}
+
+	expected := []string{
+		`13:[TestApp.Accounts.Users] describe "get_user!/1" test "returns the user" tags: accounts setup: create_user`,
+		`18:[TestApp.Accounts.Users] describe "get_user!/1" test "raises for missing users" tags: integration, accounts setup: create_user`,
+		`31:[TestApp.Accounts.Users] describe "update_user/2" test "updates the username" tags: slow, accounts, integration setup: create_user, setup@26`,
+	}
+
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %v want %v", output, expected)
+	}
+}
+
+func TestSearchTestCasesByTagAndCall(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/users_test.exs")
+	input := &SearchInput{
+		SearchType: SearchTypeTest,
+		Tags:       []string{":integration"},
+		Calls:      "Users.update_user",
+	}
+
+	matches, err := searchTestCases(root, contents, input)
+	if err != nil {
+		t.Errorf("search test cases failed: %v", err)
+	}
+
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches want 1", len(matches))
+	}
+
+	test := matches[0].(TestCase)
+	expectedCalls := []FnCall{
+		{ModulePath: "TestApp.Accounts.Users", Name: "update_user", Contents: "Users.update_user(user, attrs)", Line: 32},
+	}
+
+	if test.Name != "updates the username" || !reflect.DeepEqual(test.Calls, expectedCalls) {
+		t.Errorf("got %+v want updates the username calling %+v", test, expectedCalls)
+	}
+}
+
+func TestSearchTestCasesByName(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/users_test.exs")
+	input := &SearchInput{
+		SearchType:  SearchTypeTest,
+		SearchTerms: "get_user!",
+	}
+
+	matches, err := searchTestCases(root, contents, input)
+	if err != nil {
+		t.Errorf("search test cases failed: %v", err)
+	}
+
+	if len(matches) != 2 {
+		t.Errorf("got %d matches want 2", len(matches))
+	}
+}
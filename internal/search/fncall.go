@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	sitter "github.com/smacker/go-tree-sitter"
-	"github.com/smacker/go-tree-sitter/elixir"
 )
 
 type FnCall struct {
@@ -40,7 +39,7 @@ var remoteFnCallQuery string
 // Generate a list of all module aliases. Any aliases that are group together in a tuple
 // like Module.{Sub1, Sub2} are separated into multiple entries.
 func parseAliases(root *sitter.Node, contents []byte) ([]Alias, error) {
-	query, err := sitter.NewQuery([]byte(aliasQuery), elixir.GetLanguage())
+	query, err := newQuery(aliasQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +82,7 @@ func parseAliases(root *sitter.Node, contents []byte) ([]Alias, error) {
 
 // look for as: to use as the alias. Otherwise just use the last segment of the module path
 func parseAliasAs(node *sitter.Node, contents []byte, modulePath string) (string, error) {
-	query, err := sitter.NewQuery([]byte(aliasAsQuery), elixir.GetLanguage())
+	query, err := newQuery(aliasAsQuery)
 	if err != nil {
 		return "", err
 	}
@@ -145,7 +144,7 @@ func multipleAliases(node *sitter.Node, contents []byte) []Alias {
 
 // Generate a list of all remote functions like Module.fn_call()
 func parseRemoteCalls(root *sitter.Node, contents []byte, aliases []Alias) ([]FnCall, error) {
-	query, err := sitter.NewQuery([]byte(remoteFnCallQuery), elixir.GetLanguage())
+	query, err := newQuery(remoteFnCallQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -225,6 +224,16 @@ func searchFnCalls(root *sitter.Node, contents []byte, input *SearchInput) ([]Re
 		return nil, err
 	}
 
+	if input.Doctests {
+		doctestCalls, err := parseDoctestCalls(root, contents, aliases)
+		if err != nil {
+			return nil, err
+		}
+
+		fnCalls = append(fnCalls, doctestCalls...)
+		sortFnCalls(fnCalls)
+	}
+
 	matching := []ResultsFormatter{}
 	for _, fn := range fnCalls {
 		fullFnCall := fmt.Sprintf("%s.%s", fn.ModulePath, fn.Name)
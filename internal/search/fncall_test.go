@@ -11,6 +11,10 @@ import (
 )
 
 func readTestFile(t *testing.T) (*sitter.Node, []byte) {
+	return parseTestFile(t, "testdata/users.ex")
+}
+
+func parseTestFile(t *testing.T, file string) (*sitter.Node, []byte) {
 	lang := elixir.GetLanguage()
 
 	// setup the parser
@@ -18,7 +22,7 @@ func readTestFile(t *testing.T) (*sitter.Node, []byte) {
 	parser.SetLanguage(lang)
 
 	// read the file
-	contents, err := os.ReadFile("testdata/users.ex")
+	contents, err := os.ReadFile(file)
 	if err != nil {
 		t.Errorf("Unable to read file, %v", err)
 	}
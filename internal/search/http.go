@@ -0,0 +1,192 @@
+package search
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+//go:embed web/index.html
+var indexPage []byte
+
+// results are paginated with perPage results per page, which can be raised to maxPerPage
+const (
+	defaultPerPage = 50
+	maxPerPage     = 500
+)
+
+type httpSearchResult struct {
+	Path  string `json:"path"`
+	Match string `json:"match"`
+}
+
+type httpSearchResponse struct {
+	Mode    string             `json:"mode"`
+	Query   string             `json:"query"`
+	Page    int                `json:"page"`
+	PerPage int                `json:"perPage"`
+	Total   int                `json:"total"`
+	Results []httpSearchResult `json:"results"`
+}
+
+// ListenHTTP serves a JSON search API and a page for browsing results on addr until ctx is
+// done. Searches are made with GET /search?mode=fncall&q=Repo.update, taking the search
+// flags as query params along with page and per_page.
+func ListenHTTP(ctx context.Context, dir string, addr string) error {
+	index, err := newLiveIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: newHTTPHandler(index)}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Printf("Serving %s on http://%s\n", dir, listener.Addr())
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func newHTTPHandler(index *liveIndex) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexPage)
+	})
+
+	mux.HandleFunc("GET /search", func(w http.ResponseWriter, r *http.Request) {
+		res, err := httpSearch(r.Context(), index, r.URL.Query())
+
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": rpcErr.Message})
+		} else if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		} else {
+			writeJSON(w, http.StatusOK, res)
+		}
+	})
+
+	return mux
+}
+
+// httpSearch runs the search given in the query params of a request and returns the
+// requested page of results. Bad params are returned as an *rpcError.
+func httpSearch(ctx context.Context, index *liveIndex, query url.Values) (*httpSearchResponse, error) {
+	page, err := intParam(query, "page", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	perPage, err := intParam(query, "per_page", defaultPerPage)
+	if err != nil {
+		return nil, err
+	}
+	perPage = min(perPage, maxPerPage)
+
+	params := searchParams{
+		Mode:  query.Get("mode"),
+		Query: query.Get("q"),
+		Calls: query.Get("calls"),
+	}
+	for _, sigil := range query["sigil"] {
+		params.Sigils = append(params.Sigils, strings.Split(sigil, ",")...)
+	}
+	for _, tag := range query["tag"] {
+		params.Tags = append(params.Tags, strings.Split(tag, ",")...)
+	}
+
+	flags := map[string]*bool{
+		"literal_only":   &params.LiteralOnly,
+		"interpolations": &params.Interpolations,
+		"charlists":      &params.Charlists,
+		"undocumented":   &params.Undocumented,
+		"doctests":       &params.Doctests,
+	}
+	for name, flag := range flags {
+		if value := query.Get(name); value != "" {
+			if *flag, err = strconv.ParseBool(value); err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid %s: %s", name, value)}
+			}
+		}
+	}
+
+	input, err := params.searchInput(index.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := index.refresh(); err != nil {
+		return nil, err
+	}
+
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	res := &httpSearchResponse{
+		Mode:    params.Mode,
+		Query:   params.Query,
+		Page:    page,
+		PerPage: perPage,
+		Results: []httpSearchResult{},
+	}
+
+	first := (page - 1) * perPage
+	err = streamSearch(ctx, input, index.watcher.files, func(fileResults FileResults) error {
+		for _, match := range fileResults.Matches {
+			if res.Total >= first && res.Total < first+perPage {
+				res.Results = append(res.Results, httpSearchResult{Path: fileResults.Path, Match: match})
+			}
+			res.Total++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// intParam reads a positive integer from the query params
+func intParam(query url.Values, name string, value int) (int, error) {
+	if query.Get(name) == "" {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(query.Get(name))
+	if err != nil || value < 1 {
+		return 0, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid %s: %s", name, query.Get(name))}
+	}
+
+	return value, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := marshalJSON(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,91 @@
+package search
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPSearch(t *testing.T) {
+	index, err := newLiveIndex(writeTestProject(t))
+	if err != nil {
+		t.Fatalf("new live index failed: %v", err)
+	}
+	handler := newHTTPHandler(index)
+
+	tests := []struct {
+		url      string
+		status   int
+		total    int
+		page     int
+		expected []httpSearchResult
+	}{
+		{"/search?mode=fncall&q=Repo", 200, 3, 1, []httpSearchResult{
+			{Path: "users.ex", Match: "12:Repo.get!(User, id)"},
+			{Path: "users.ex", Match: "15:Repo.get_by(User, username: username)"},
+			{Path: "users.ex", Match: "22:Repo.update()"},
+		}},
+		{"/search?mode=fncall&q=Repo&page=2&per_page=2", 200, 3, 2, []httpSearchResult{
+			{Path: "users.ex", Match: "22:Repo.update()"},
+		}},
+		{"/search?mode=fncall&q=Repo&page=3&per_page=2", 200, 3, 3, []httpSearchResult{}},
+		{"/search?mode=str&q=Hello&literal_only=true", 200, 1, 1, []httpSearchResult{
+			{Path: "users.ex", Match: `36:"Hello #{user.first}, your favorite color is: #{color}"`},
+		}},
+		{"/search?mode=nope&q=Repo", 400, 0, 0, nil},
+		{"/search?mode=fncall", 400, 0, 0, nil},
+		{"/search?mode=fncall&q=Repo&page=0", 400, 0, 0, nil},
+		{"/search?mode=str&q=Hello&charlists=maybe", 400, 0, 0, nil},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", test.url, nil))
+
+		if rec.Code != test.status {
+			t.Errorf("%s: got status %d want %d: %s", test.url, rec.Code, test.status, rec.Body)
+			continue
+		}
+
+		if test.status != http.StatusOK {
+			continue
+		}
+
+		res := httpSearchResponse{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Unable to unmarshal response, %v", err)
+		}
+
+		if res.Total != test.total || res.Page != test.page || len(res.Results) != len(test.expected) {
+			t.Errorf("%s: got %+v", test.url, res)
+			continue
+		}
+
+		for i, result := range res.Results {
+			if result != test.expected[i] {
+				t.Errorf("%s: got %+v want %+v", test.url, result, test.expected[i])
+			}
+		}
+	}
+}
+
+func TestHTTPPage(t *testing.T) {
+	index, err := newLiveIndex(writeTestProject(t))
+	if err != nil {
+		t.Fatalf("new live index failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	newHTTPHandler(index).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<title>exarch</title>") {
+		t.Errorf("got %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	newHTTPHandler(index).ServeHTTP(rec, httptest.NewRequest("GET", "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got %d want 404", rec.Code)
+	}
+}
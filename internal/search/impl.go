@@ -0,0 +1,344 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+type Impl struct {
+	Module    string
+	Behaviour string
+	Line      uint32
+	Contents  string
+	Callbacks []ImplCallback
+}
+
+// ImplCallback is a function marked with @impl. Unknown is set when the behaviour's
+// source is available and it doesn't define a matching callback.
+type ImplCallback struct {
+	Name    string
+	Arity   int
+	Line    uint32
+	Unknown bool
+}
+
+func (i Impl) Format() string {
+	lines := []string{fmt.Sprintf("%d:%s %s", i.Line, i.Module, i.Contents)}
+	for _, callback := range i.Callbacks {
+		line := fmt.Sprintf("  %d:%s/%d", callback.Line, callback.Name, callback.Arity)
+		if callback.Unknown {
+			line += " (not a known callback)"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// implAttr is a function definition preceded by @impl. Behaviour is empty for @impl true.
+type implAttr struct {
+	Module    string
+	Behaviour string
+	Def       FuncDef
+}
+
+//go:embed queries/behaviour.scm
+var behaviourQuery string
+
+//go:embed queries/impl_attr.scm
+var implAttrQuery string
+
+//go:embed queries/callback_def.scm
+var callbackDefQuery string
+
+// Generate a list of all modules that declare a behaviour with @behaviour or use.
+func parseBehaviours(root *sitter.Node, contents []byte, modules []Module, aliases []Alias) ([]Impl, error) {
+	query, err := newQuery(behaviourQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	impls := []Impl{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var declaration, keyword, behaviour *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "declaration":
+				declaration = capture.Node
+			case "keyword":
+				keyword = capture.Node
+			case "behaviour":
+				behaviour = capture.Node
+			}
+		}
+
+		if declaration == nil || keyword == nil || behaviour == nil {
+			continue
+		}
+
+		// @behaviour is a module attribute, use is a plain call
+		parent := declaration.Parent()
+		isAttr := parent != nil && parent.Type() == "unary_operator"
+		if keyword.Content(contents) == "behaviour" {
+			if !isAttr {
+				continue
+			}
+			declaration = parent
+		} else if isAttr {
+			continue
+		}
+
+		module := enclosingModule(declaration, modules)
+		if module == nil {
+			continue
+		}
+
+		// only keep the first line of use calls that pass options over multiple lines
+		declContents := declaration.Content(contents)
+		declContents = strings.TrimSpace(strings.SplitN(declContents, "\n", 2)[0])
+
+		impls = append(impls, Impl{
+			Module:    module.Name,
+			Behaviour: findFullModulePath(behaviour.Content(contents), aliases),
+			Line:      declaration.StartPoint().Row,
+			Contents:  declContents,
+		})
+	}
+
+	return impls, nil
+}
+
+// Generate a list of all function definitions annotated with @impl true or @impl Behaviour.
+func parseImplAttrs(root *sitter.Node, contents []byte, defs []FuncDef, aliases []Alias) ([]implAttr, error) {
+	query, err := newQuery(implAttrQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	attrs := []implAttr{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var attr, value *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "impl":
+				attr = capture.Node
+			case "value":
+				value = capture.Node
+			}
+		}
+
+		if attr == nil || value == nil || value.Content(contents) == "false" {
+			continue
+		}
+
+		behaviour := ""
+		if value.Type() == "alias" {
+			behaviour = findFullModulePath(value.Content(contents), aliases)
+		}
+
+		// the annotated function is the next definition, skipping over other attributes
+		// and comments
+		for sibling := attr.NextNamedSibling(); sibling != nil; sibling = sibling.NextNamedSibling() {
+			if idx := slices.IndexFunc(defs, func(def FuncDef) bool { return def.node.Equal(sibling) }); idx >= 0 {
+				attrs = append(attrs, implAttr{
+					Module:    defs[idx].Module,
+					Behaviour: behaviour,
+					Def:       defs[idx],
+				})
+				break
+			}
+
+			if sibling.Type() != "unary_operator" && sibling.Type() != "comment" {
+				break
+			}
+		}
+	}
+
+	return attrs, nil
+}
+
+// Generate a map of modules to the name/arity of each @callback and @macrocallback
+// they define.
+func parseCallbacks(root *sitter.Node, contents []byte, modules []Module) (map[string][]string, error) {
+	query, err := newQuery(callbackDefQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	callbacks := map[string][]string{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var attr, spec *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "callback":
+				attr = capture.Node
+			case "spec":
+				spec = capture.Node
+			}
+		}
+
+		if attr == nil || spec == nil {
+			continue
+		}
+
+		module := enclosingModule(attr, modules)
+		if module == nil {
+			continue
+		}
+
+		// specs look like name(args) :: return, possibly followed by a when clause
+		for spec.Type() == "binary_operator" {
+			spec = spec.ChildByFieldName("left")
+		}
+
+		name, arity := funcHead(spec, contents)
+		callbacks[module.Name] = append(callbacks[module.Name], fmt.Sprintf("%s/%d", name, arity))
+	}
+
+	return callbacks, nil
+}
+
+// Walk every file in the project to find behaviours and their callbacks.
+func parseProjectCallbacks(dir string) (map[string][]string, error) {
+	callbacks := map[string][]string{}
+	err := walkElixirFiles(dir, func(path string) error {
+		root, contents, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+
+		modules, err := parseModules(root, contents)
+		if err != nil {
+			return err
+		}
+
+		fileCallbacks, err := parseCallbacks(root, contents, modules)
+		if err != nil {
+			return err
+		}
+
+		for module, names := range fileCallbacks {
+			callbacks[module] = append(callbacks[module], names...)
+		}
+
+		return nil
+	})
+
+	return callbacks, err
+}
+
+func searchImpls(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	aliases, err := parseAliases(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := parseFuncDefs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	behaviours, err := parseBehaviours(root, contents, modules, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := parseImplAttrs(root, contents, defs, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []ResultsFormatter{}
+	seen := map[string]bool{}
+	for _, impl := range behaviours {
+		key := impl.Module + " " + impl.Behaviour
+		if !matchesModule(impl.Behaviour, input.SearchTerms) || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		known, hasSource := input.callbacks[impl.Behaviour]
+		for _, attr := range attrs {
+			if attr.Module != impl.Module || !belongsToBehaviour(attr, impl, behaviours, input.callbacks) {
+				continue
+			}
+
+			impl.Callbacks = append(impl.Callbacks, ImplCallback{
+				Name:    attr.Def.Name,
+				Arity:   attr.Def.Arity,
+				Line:    attr.Def.Line,
+				Unknown: hasSource && !slices.Contains(known, attr.Def.Signature()),
+			})
+		}
+
+		matching = append(matching, impl)
+	}
+
+	return matching, nil
+}
+
+// check if an @impl function implements a callback of the behaviour. @impl true is
+// ambiguous when a module has several behaviours, so it is only given to this behaviour
+// when no other behaviour of the module could be defining the callback.
+func belongsToBehaviour(attr implAttr, impl Impl, behaviours []Impl, callbacks map[string][]string) bool {
+	if attr.Behaviour != "" {
+		return attr.Behaviour == impl.Behaviour
+	}
+
+	if slices.Contains(callbacks[impl.Behaviour], attr.Def.Signature()) {
+		return true
+	}
+
+	for _, other := range behaviours {
+		if other.Module != impl.Module || other.Behaviour == impl.Behaviour {
+			continue
+		}
+
+		// behaviours without source in the project could define anything
+		known, hasSource := callbacks[other.Behaviour]
+		if !hasSource || slices.Contains(known, attr.Def.Signature()) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseModules(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/users.ex")
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		t.Errorf("parse modules failed: %v", err)
+	}
+
+	if len(modules) != 1 || modules[0].Name != "TestApp.Accounts.Users" || modules[0].Line != 0 {
+		t.Errorf("got %+v want TestApp.Accounts.Users", modules)
+	}
+}
+
+func TestParseCallbacks(t *testing.T) {
+	callbacks, err := parseProjectCallbacks("testdata")
+	if err != nil {
+		t.Errorf("parse callbacks failed: %v", err)
+	}
+
+	expected := map[string][]string{
+		"TestApp.Notifier": {"notify/2", "channel/0"},
+	}
+
+	if !reflect.DeepEqual(callbacks, expected) {
+		t.Errorf("got %v want %v", callbacks, expected)
+	}
+}
+
+func TestSearchImpls(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/worker.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeImpl,
+		SearchTerms: "Notifier",
+		callbacks: map[string][]string{
+			"TestApp.Notifier": {"notify/2", "channel/0"},
+		},
+	}
+
+	matches, err := searchImpls(root, contents, input)
+	if err != nil {
+		t.Errorf("search impls failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Impl{
+			Module:    "TestApp.EmailWorker",
+			Behaviour: "TestApp.Notifier",
+			Line:      5,
+			Contents:  "@behaviour Notifier",
+			Callbacks: []ImplCallback{
+				{Name: "notify", Arity: 2, Line: 21},
+				{Name: "channel", Arity: 0, Line: 27},
+				{Name: "deliver", Arity: 1, Line: 30, Unknown: true},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %+v want %+v", matches, expected)
+	}
+}
+
+func TestSearchImplsUse(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/worker.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeImpl,
+		SearchTerms: "GenServer",
+		callbacks: map[string][]string{
+			"TestApp.Notifier": {"notify/2", "channel/0"},
+		},
+	}
+
+	matches, err := searchImpls(root, contents, input)
+	if err != nil {
+		t.Errorf("search impls failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Impl{
+			Module:    "TestApp.EmailWorker",
+			Behaviour: "GenServer",
+			Line:      1,
+			Contents:  "use GenServer",
+			Callbacks: []ImplCallback{
+				{Name: "init", Arity: 1, Line: 12},
+				{Name: "handle_cast", Arity: 2, Line: 15},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %+v want %+v", matches, expected)
+	}
+}
@@ -0,0 +1,232 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// the on disk index lives in this directory at the root of the project
+const indexDir = ".exarch"
+
+const indexFileName = "index.gob"
+
+// bump when FileIndex changes so old indexes are rebuilt instead of misread
+const indexVersion = 3
+
+type diskIndex struct {
+	Version int
+	Files   map[string]*FileIndex // keyed by the path relative to the project
+}
+
+func indexPath(dir string) string {
+	return filepath.Join(dir, indexDir, indexFileName)
+}
+
+// loadIndex reads the on disk index for a project. It returns nil when the project hasn't
+// been indexed, and an empty index when the index was written by another version.
+func loadIndex(dir string) (*diskIndex, error) {
+	f, err := os.Open(indexPath(dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	index := &diskIndex{}
+	if err := gob.NewDecoder(f).Decode(index); err != nil || index.Version != indexVersion {
+		return &diskIndex{Version: indexVersion, Files: map[string]*FileIndex{}}, nil
+	}
+
+	return index, nil
+}
+
+// saveIndex writes the index for a project. The index is written to a temporary file
+// first so a reader never sees a partial index.
+func saveIndex(dir string, files []*FileIndex) error {
+	index := diskIndex{Version: indexVersion, Files: map[string]*FileIndex{}}
+	for _, file := range files {
+		relPath, err := filepath.Rel(dir, file.Path)
+		if err != nil {
+			return err
+		}
+		index.Files[relPath] = file
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, indexDir), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(dir, indexDir), indexFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(index); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), indexPath(dir))
+}
+
+// refreshIndex indexes every elixir file under dir, reusing entries from the on disk index
+// for files whose modification time and size, or content hash, haven't changed. The index
+// can be nil to parse every file. It returns the number of index entries that changed.
+func refreshIndex(dir string, index *diskIndex) ([]*FileIndex, int, error) {
+	files := []*FileIndex{}
+	updated := 0
+	err := walkElixirFiles(dir, func(path string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		var cached *FileIndex
+		if index != nil {
+			if relPath, err := filepath.Rel(dir, path); err == nil {
+				cached = index.Files[relPath]
+			}
+		}
+
+		// unchanged files don't need to be read
+		if cached != nil && cached.ModTime == info.ModTime().UnixNano() && cached.Size == info.Size() {
+			cached.Path = path
+			files = append(files, cached)
+			return nil
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// files that were touched but not changed only need their modification time updated
+		hash := contentHash(contents)
+		if cached != nil && cached.Hash == hash {
+			cached.Path = path
+			cached.ModTime = info.ModTime().UnixNano()
+			cached.Size = info.Size()
+			files = append(files, cached)
+			updated++
+			return nil
+		}
+
+		file, err := indexFile(path, contents)
+		if err != nil {
+			return err
+		}
+
+		file.Hash = hash
+		file.ModTime = info.ModTime().UnixNano()
+		file.Size = info.Size()
+		files = append(files, file)
+		updated++
+		return nil
+	})
+
+	// files that were deleted need to be dropped from the index
+	if index != nil && len(index.Files) != len(files) {
+		updated++
+	}
+
+	return files, updated, err
+}
+
+func contentHash(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// BuildIndex creates or updates the on disk index for a project so later searches only
+// need to parse files that have changed.
+func BuildIndex(dir string) error {
+	index, err := loadIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	if index == nil {
+		index = &diskIndex{Version: indexVersion, Files: map[string]*FileIndex{}}
+	}
+
+	files, updated, err := refreshIndex(dir, index)
+	if err != nil {
+		return err
+	}
+
+	if err := saveIndex(dir, files); err != nil {
+		return err
+	}
+
+	fmt.Printf("Indexed %d files (%d updated) in %s\n", len(files), min(updated, len(files)), indexPath(dir))
+	return nil
+}
+
+// indexedFiles returns the up to date index of every file in the project keyed by path, or
+// nil when the project doesn't have an on disk index.
+func indexedFiles(dir string) (map[string]*FileIndex, error) {
+	if _, err := os.Stat(indexPath(dir)); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	files, err := indexProject(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := map[string]*FileIndex{}
+	for _, file := range files {
+		indexed[file.Path] = file
+	}
+
+	return indexed, nil
+}
+
+// searchIndex answers a search from the index of a file when it can, without parsing the
+// file. The second return value is false when the file has to be parsed. Only fncall,
+// str and doc searches use the index, the modes listed in the index command's help.
+func searchIndex(file *FileIndex, input *SearchInput) ([]string, bool) {
+	switch input.SearchType {
+	case SearchTypeFnCall:
+		if input.Doctests {
+			return nil, false
+		}
+
+		output := []string{}
+		for _, fn := range file.Calls {
+			if strings.Contains(fmt.Sprintf("%s.%s", fn.ModulePath, fn.Name), input.SearchTerms) {
+				output = append(output, fn.Format())
+			}
+		}
+
+		return output, true
+	case SearchTypeStr, SearchTypeDoc:
+		if input.Undocumented {
+			return nil, false
+		}
+
+		// the file only needs to be searched if one of its strings contains the terms
+		for _, str := range file.Strings {
+			if strings.Contains(str, input.SearchTerms) {
+				return nil, false
+			}
+		}
+
+		return []string{}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,149 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeTestProject copies users.ex and accounts.ex from testdata to a new project
+func writeTestProject(t *testing.T) string {
+	files := map[string]string{}
+	for _, file := range []string{"users.ex", "accounts.ex"} {
+		files[file] = string(mustRead(t, filepath.Join("testdata", file)))
+	}
+
+	return writeProjectFiles(t, files)
+}
+
+// writeProjectFiles writes the files, keyed by name, to a new project
+func writeProjectFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	addProjectFiles(t, dir, files)
+	return dir
+}
+
+// addProjectFiles writes the files, keyed by name, to an existing project
+func addProjectFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatalf("Unable to write file, %v", err)
+		}
+	}
+}
+
+func TestBuildIndex(t *testing.T) {
+	dir := writeTestProject(t)
+	if err := BuildIndex(dir); err != nil {
+		t.Fatalf("build index failed: %v", err)
+	}
+
+	index, err := loadIndex(dir)
+	if err != nil || index == nil {
+		t.Fatalf("load index failed: %v", err)
+	}
+
+	if len(index.Files) != 2 || index.Files["users.ex"] == nil || index.Files["accounts.ex"] == nil {
+		t.Errorf("got %v want users.ex and accounts.ex", index.Files)
+	}
+
+	// the index is used in place of parsing
+	files, err := indexProject(dir)
+	if err != nil {
+		t.Fatalf("index project failed: %v", err)
+	}
+
+	expected, err := indexFile(filepath.Join(dir, "users.ex"), mustRead(t, filepath.Join(dir, "users.ex")))
+	if err != nil {
+		t.Fatalf("index file failed: %v", err)
+	}
+
+	var users *FileIndex
+	for _, file := range files {
+		if file.Path == filepath.Join(dir, "users.ex") {
+			users = file
+		}
+	}
+
+	if users == nil || !reflect.DeepEqual(users.Refs, expected.Refs) || !reflect.DeepEqual(users.Calls, expected.Calls) {
+		t.Errorf("got %+v want %+v", users, expected)
+	}
+}
+
+func TestRefreshIndex(t *testing.T) {
+	dir := writeTestProject(t)
+	if err := BuildIndex(dir); err != nil {
+		t.Fatalf("build index failed: %v", err)
+	}
+
+	index, _ := loadIndex(dir)
+	if _, updated, _ := refreshIndex(dir, index); updated != 0 {
+		t.Errorf("got %d updated files want 0", updated)
+	}
+
+	// change one file and make sure only it is parsed again
+	path := filepath.Join(dir, "users.ex")
+	contents := append(mustRead(t, path), []byte("\ndefmodule TestApp.Extra do\nend\n")...)
+	if err := os.WriteFile(path, contents, 0o644); err != nil {
+		t.Fatalf("Unable to write file, %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	files, updated, err := refreshIndex(dir, index)
+	if err != nil {
+		t.Fatalf("refresh index failed: %v", err)
+	}
+
+	if updated != 1 {
+		t.Errorf("got %d updated files want 1", updated)
+	}
+
+	found := false
+	for _, file := range files {
+		for _, module := range file.Modules {
+			found = found || module.Name == "TestApp.Extra"
+		}
+	}
+
+	if !found {
+		t.Errorf("expected the changed file to be parsed again")
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	contents := mustRead(t, "testdata/users.ex")
+	file, err := indexFile("testdata/users.ex", contents)
+	if err != nil {
+		t.Fatalf("index file failed: %v", err)
+	}
+
+	input := &SearchInput{SearchType: SearchTypeFnCall, SearchTerms: "process"}
+	res, ok := searchIndex(file, input)
+	expected, _ := searchFile("testdata/users.ex", input)
+	if !ok || !reflect.DeepEqual(res, expected) {
+		t.Errorf("got %v want %v", res, expected)
+	}
+
+	// files without a matching string don't need to be parsed
+	input = &SearchInput{SearchType: SearchTypeStr, SearchTerms: "not in the file"}
+	if res, ok := searchIndex(file, input); !ok || len(res) != 0 {
+		t.Errorf("got %v %v want no results", res, ok)
+	}
+
+	input = &SearchInput{SearchType: SearchTypeStr, SearchTerms: "string"}
+	if _, ok := searchIndex(file, input); ok {
+		t.Errorf("expected the file to need parsing")
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unable to read file, %v", err)
+	}
+
+	return contents
+}
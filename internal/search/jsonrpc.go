@@ -0,0 +1,207 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// error codes from the JSON-RPC and LSP specifications
+const (
+	rpcParseError           = -32700
+	rpcInvalidRequest       = -32600
+	rpcMethodNotFound       = -32601
+	rpcInvalidParams        = -32602
+	rpcInternalError        = -32603
+	rpcServerNotInitialized = -32002
+	rpcRequestCancelled     = -32800
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification when it doesn't have an id.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+func (r *rpcRequest) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      json.RawMessage  `json:"id"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// rpcError is an error sent in a response. Handlers can return one to choose the error
+// code, any other error is sent as an internal error.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcConn reads and writes JSON-RPC messages framed with a Content-Length header, as used
+// by the language server protocol, or one message per line. Writes are safe to make from
+// several goroutines.
+type rpcConn struct {
+	reader *textproto.Reader
+	writer io.Writer
+	lines  bool
+	mu     sync.Mutex
+}
+
+func newRPCConn(in io.Reader, out io.Writer) *rpcConn {
+	return &rpcConn{reader: textproto.NewReader(bufio.NewReader(in)), writer: out}
+}
+
+// newLineRPCConn creates a connection sending one message per line.
+func newLineRPCConn(in io.Reader, out io.Writer) *rpcConn {
+	conn := newRPCConn(in, out)
+	conn.lines = true
+	return conn
+}
+
+// read reads the next message. Messages that aren't valid JSON are returned as an
+// *rpcError so the caller can report them and keep reading.
+func (c *rpcConn) read() (*rpcRequest, error) {
+	body, err := c.readMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	req := &rpcRequest{}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, &rpcError{Code: rpcParseError, Message: err.Error()}
+	}
+
+	return req, nil
+}
+
+// readMessage reads the body of the next message
+func (c *rpcConn) readMessage() ([]byte, error) {
+	if c.lines {
+		for {
+			line, err := c.reader.ReadLineBytes()
+			if err != nil {
+				return nil, err
+			}
+
+			// blank lines between messages are ignored
+			if len(bytes.TrimSpace(line)) > 0 {
+				return line, nil
+			}
+		}
+	}
+
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader.R, body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func (c *rpcConn) write(msg any) error {
+	body, err := marshalJSON(msg)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lines {
+		_, err := c.writer.Write(append(body, '\n'))
+		return err
+	}
+
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+
+	_, err = c.writer.Write(body)
+	return err
+}
+
+// reply responds to a request with either its result or an error.
+func (c *rpcConn) reply(id json.RawMessage, result any, err error) error {
+	res := rpcResponse{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		res.Error = rpcErr
+
+		return c.write(res)
+	}
+
+	body, err := marshalJSON(result)
+	if err != nil {
+		return err
+	}
+
+	raw := json.RawMessage(body)
+	res.Result = &raw
+	return c.write(res)
+}
+
+// notify sends a notification, which the other side doesn't respond to.
+func (c *rpcConn) notify(method string, params any) error {
+	return c.write(rpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// marshalJSON is json.Marshal without escaping the & < > in code.
+func marshalJSON(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// unmarshalParams decodes the params of a request, reporting bad params with the invalid
+// params error code.
+func unmarshalParams(req *rpcRequest, params any) error {
+	if len(req.Params) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package search
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// AliasLint is a problem with an alias: unused, duplicate, shadowed or groupable
+type AliasLint struct {
+	Line    uint32
+	Kind    string
+	Message string
+}
+
+func (l AliasLint) Format() string {
+	return fmt.Sprintf("%d:[%s] %s", l.Line, l.Kind, l.Message)
+}
+
+// aliasDecl is an alias along with the module it's declared in and the alias node
+type aliasDecl struct {
+	Alias
+	scope *sitter.Node // The module the alias is declared in, or the file outside of modules
+	node  *sitter.Node // The alias, or the prefix of alias Prefix.{A, B}
+}
+
+// explicitAs checks if the alias was given a name with as:
+func (d aliasDecl) explicitAs() bool {
+	return d.As != d.ModulePath[strings.LastIndex(d.ModulePath, ".")+1:]
+}
+
+// LintAliases prints the alias problems in every file of the project in dir and returns
+// the number found.
+func LintAliases(dir string) (int, error) {
+	count := 0
+	err := walkElixirFiles(dir, func(path string) error {
+		root, contents, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+
+		lints, err := lintAliases(root, contents)
+		if err != nil || len(lints) == 0 {
+			return err
+		}
+
+		relFile, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(relFile)
+		for _, lint := range lints {
+			fmt.Println(lint.Format())
+		}
+		fmt.Println("")
+
+		count += len(lints)
+		return nil
+	})
+
+	return count, err
+}
+
+// lintAliases finds aliases that are never referenced in the scope they're declared in,
+// aliases declared twice, aliases with the same name as an earlier alias and aliases with
+// a common prefix that could be grouped into alias Prefix.{A, B}.
+func lintAliases(root *sitter.Node, contents []byte) ([]AliasLint, error) {
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := newQuery(aliasRefQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	scopeOf := func(node *sitter.Node) *sitter.Node {
+		if module := enclosingModule(node, modules); module != nil {
+			return module.node
+		}
+		return root
+	}
+
+	// the aliases declared and every other alias node, which could reference one
+	decls := []aliasDecl{}
+	refs := []*sitter.Node{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, capture := range match.Captures {
+			node := capture.Node
+			switch aliasContext(node, contents) {
+			case "alias":
+				alias, err := singleAlias(node.Parent(), contents)
+				if err != nil {
+					return nil, err
+				}
+				decls = append(decls, aliasDecl{Alias: alias, scope: scopeOf(node), node: node})
+				refs = append(refs, node)
+			case "alias_prefix":
+				for _, alias := range multipleAliases(node.Parent(), contents) {
+					decls = append(decls, aliasDecl{Alias: alias, scope: scopeOf(node), node: node})
+				}
+				refs = append(refs, node)
+			case "":
+				refs = append(refs, node)
+			}
+		}
+	}
+
+	lints := []AliasLint{}
+	for i, decl := range decls {
+		line := decl.Line - 1
+
+		// an alias is used by references in its scope, including declarations of other
+		// aliases like alias Accounts.User
+		used := false
+		for _, ref := range refs {
+			first := strings.SplitN(ref.Content(contents), ".", 2)[0]
+			if first == decl.As && !ref.Equal(decl.node) && containsNode(decl.scope, ref) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			lints = append(lints, AliasLint{Line: line, Kind: "unused", Message: fmt.Sprintf("alias %s is never used", decl.ModulePath)})
+		}
+
+		for _, earlier := range decls[:i] {
+			if !earlier.scope.Equal(decl.scope) {
+				continue
+			}
+
+			if earlier.ModulePath == decl.ModulePath && earlier.As == decl.As {
+				lints = append(lints, AliasLint{Line: line, Kind: "duplicate", Message: fmt.Sprintf("alias %s is already declared on line %d", decl.ModulePath, earlier.Line-1)})
+				break
+			}
+
+			if earlier.As == decl.As {
+				lints = append(lints, AliasLint{Line: line, Kind: "shadowed", Message: fmt.Sprintf("alias %s as %s shadows alias %s on line %d", decl.ModulePath, decl.As, earlier.ModulePath, earlier.Line-1)})
+				break
+			}
+		}
+	}
+
+	lints = append(lints, groupableAliases(decls)...)
+
+	sort.SliceStable(lints, func(i, j int) bool { return lints[i].Line < lints[j].Line })
+	return lints, nil
+}
+
+// groupableAliases finds aliases in the same scope with the same prefix, reported once at
+// the first of them with the grouped alias to use instead. Aliases given a name with as:
+// can't be grouped.
+func groupableAliases(decls []aliasDecl) []AliasLint {
+	type group struct {
+		scope  *sitter.Node
+		prefix string
+	}
+
+	groups := []group{}
+	members := map[group][]aliasDecl{}
+	for _, decl := range decls {
+		idx := strings.LastIndex(decl.ModulePath, ".")
+		if idx < 0 || decl.explicitAs() {
+			continue
+		}
+
+		key := group{scope: decl.scope, prefix: decl.ModulePath[:idx]}
+		for _, existing := range groups {
+			if existing.prefix == key.prefix && existing.scope.Equal(key.scope) {
+				key = existing
+			}
+		}
+
+		if _, ok := members[key]; !ok {
+			groups = append(groups, key)
+		}
+		members[key] = append(members[key], decl)
+	}
+
+	lints := []AliasLint{}
+	for _, key := range groups {
+		// an existing group on its own is already grouped
+		decls := members[key]
+		names := []string{}
+		declarations := map[uint32]bool{}
+		for _, decl := range decls {
+			name := decl.ModulePath[len(key.prefix)+1:]
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+			declarations[decl.node.StartByte()] = true
+		}
+
+		if len(names) < 2 || len(declarations) < 2 {
+			continue
+		}
+
+		lints = append(lints, AliasLint{
+			Line:    decls[0].Line - 1,
+			Kind:    "groupable",
+			Message: fmt.Sprintf("aliases could be grouped as alias %s.{%s}", key.prefix, strings.Join(names, ", ")),
+		})
+	}
+
+	return lints
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const lintAliasesSource = `defmodule TestApp.Web do
+  alias TestApp.Repo
+  alias TestApp.Accounts
+  alias Accounts.User
+  alias TestApp.Accounts.{Admin, Team}
+  alias TestApp.Repo
+  alias Other.Admin, as: Team
+  alias TestApp.Unused
+
+  def list, do: {Repo.all(User), %Admin{}, Team}
+
+  defmodule Nested do
+    alias TestApp.Repo
+
+    def one, do: Repo.one()
+  end
+end
+`
+
+func TestLintAliases(t *testing.T) {
+	path := filepath.Join(writeProjectFiles(t, map[string]string{"web.ex": lintAliasesSource}), "web.ex")
+
+	root, contents := parseTestFile(t, path)
+	lints, err := lintAliases(root, contents)
+	if err != nil {
+		t.Fatalf("lint aliases failed: %v", err)
+	}
+
+	// aliases are checked within the module they're declared in, so the nested module can
+	// alias Repo again
+	expected := []AliasLint{
+		{Line: 1, Kind: "groupable", Message: "aliases could be grouped as alias TestApp.{Repo, Accounts, Unused}"},
+		{Line: 5, Kind: "duplicate", Message: "alias TestApp.Repo is already declared on line 1"},
+		{Line: 6, Kind: "shadowed", Message: "alias Other.Admin as Team shadows alias TestApp.Accounts.Team on line 4"},
+		{Line: 7, Kind: "unused", Message: "alias TestApp.Unused is never used"},
+	}
+	if !reflect.DeepEqual(lints, expected) {
+		t.Errorf("got %+v want %+v", lints, expected)
+	}
+}
+
+func TestLintAliasesTestFile(t *testing.T) {
+	root, contents := readTestFile(t)
+	lints, err := lintAliases(root, contents)
+	if err != nil {
+		t.Fatalf("lint aliases failed: %v", err)
+	}
+
+	expected := []AliasLint{
+		{Line: 6, Kind: "unused", Message: "alias TestApp.Accounts.Admin is never used"},
+		{Line: 7, Kind: "unused", Message: "alias TestApp.Result is never used"},
+	}
+	if !reflect.DeepEqual(lints, expected) {
+		t.Errorf("got %+v want %+v", lints, expected)
+	}
+}
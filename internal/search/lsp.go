@@ -0,0 +1,642 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/elixir"
+)
+
+// symbol kinds from the language server protocol
+const (
+	lspSymbolModule   = 2
+	lspSymbolFunction = 12
+	lspSymbolStruct   = 23
+)
+
+type lspPosition struct {
+	Line      uint32 `json:"line"`
+	Character uint32 `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text,omitempty"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children,omitempty"`
+}
+
+type lspSymbolInformation struct {
+	Name          string      `json:"name"`
+	Kind          int         `json:"kind"`
+	Location      lspLocation `json:"location"`
+	ContainerName string      `json:"containerName,omitempty"`
+}
+
+type lspPositionParams struct {
+	TextDocument lspTextDocument `json:"textDocument"`
+	Position     lspPosition     `json:"position"`
+	Context      struct {
+		IncludeDeclaration bool `json:"includeDeclaration"`
+	} `json:"context"`
+}
+
+type lspSearchResult struct {
+	URI     string   `json:"uri"`
+	Path    string   `json:"path"`
+	Matches []string `json:"matches"`
+}
+
+// lspServer answers language server requests from an in memory index of the project. The
+// index is brought up to date before a request when files changed on disk, and files open
+// in the editor are indexed from their unsaved contents.
+type lspServer struct {
+	dir     string
+	conn    *rpcConn
+	watcher *watcher
+	open    map[string][]byte // the contents of files open in the editor, keyed by path
+
+	// changes signals when files might have changed on disk. The disk is checked before
+	// every request when it's nil.
+	changes <-chan struct{}
+	errs    <-chan error
+	stop    context.CancelFunc
+
+	// utf8 is set when the client agreed to columns in bytes. Otherwise columns are in
+	// UTF-16 code units, the protocol's default, and are converted from and to bytes.
+	utf8  bool
+	lines map[string][]string // the lines of files converted during the current request
+}
+
+// ServeLSP runs a language server for the project in dir over in and out until the client
+// exits. Columns are in bytes when the client supports it and UTF-16 otherwise.
+func ServeLSP(dir string, in io.Reader, out io.Writer) error {
+	server := &lspServer{dir: dir, conn: newRPCConn(in, out), open: map[string][]byte{}}
+	defer func() {
+		if server.stop != nil {
+			server.stop()
+		}
+	}()
+
+	for {
+		req, err := server.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if err := server.conn.reply(json.RawMessage("null"), nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := server.handle(req)
+		if req.isNotification() {
+			if err != nil {
+				server.conn.notify("window/logMessage", map[string]any{"type": 1, "message": err.Error()})
+			}
+			continue
+		}
+
+		if err := server.conn.reply(req.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) handle(req *rpcRequest) (any, error) {
+	if req.Method == "initialize" {
+		return s.initialize(req)
+	}
+
+	if s.watcher == nil {
+		return nil, &rpcError{Code: rpcServerNotInitialized, Message: "server not initialized"}
+	}
+
+	// files can change between requests, so lines are only kept for one
+	s.lines = map[string][]string{}
+
+	switch req.Method {
+	case "initialized", "shutdown", "$/cancelRequest", "$/setTrace", "textDocument/didSave":
+		return nil, nil
+	case "textDocument/didOpen":
+		params := struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.openDocument(params.TextDocument.URI, []byte(params.TextDocument.Text))
+	case "textDocument/didChange":
+		params := struct {
+			TextDocument   lspTextDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+
+		// only full syncs are asked for, so the last change has the whole document
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		return nil, s.openDocument(params.TextDocument.URI, []byte(params.ContentChanges[len(params.ContentChanges)-1].Text))
+	case "textDocument/didClose":
+		params := struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+
+		path, err := uriToPath(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		delete(s.open, path)
+		s.watcher.removeContents(path)
+		return nil, s.refresh()
+	case "workspace/symbol":
+		params := struct {
+			Query string `json:"query"`
+		}{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.workspaceSymbols(params.Query)
+	case "textDocument/documentSymbol":
+		params := lspPositionParams{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.documentSymbols(params.TextDocument.URI)
+	case "textDocument/definition":
+		params := lspPositionParams{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params)
+	case "textDocument/references":
+		params := lspPositionParams{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.references(params)
+	case "exarch/search":
+		params := searchParams{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.search(params)
+	}
+
+	if req.isNotification() {
+		return nil, nil
+	}
+
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+// initialize indexes the workspace the client opened and tells it what the server supports
+func (s *lspServer) initialize(req *rpcRequest) (any, error) {
+	params := struct {
+		RootURI      string `json:"rootUri"`
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}{}
+	if err := unmarshalParams(req, &params); err != nil {
+		return nil, err
+	}
+
+	if params.RootURI != "" {
+		dir, err := uriToPath(params.RootURI)
+		if err != nil {
+			return nil, err
+		}
+		s.dir = dir
+	}
+
+	// the on disk index is only kept up to date when the project already has one
+	index, err := loadIndex(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s.watcher, err = newWatcher(s.dir, index != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// without file system events the disk is checked before every request instead
+	ctx, stop := context.WithCancel(context.Background())
+	changes, errs, err := watchChanges(ctx, &WatchInput{Dir: s.dir})
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	s.changes, s.errs, s.stop = changes, errs, stop
+
+	capabilities := map[string]any{
+		"textDocumentSync": map[string]any{
+			"openClose": true,
+			"change":    1, // full
+		},
+		"workspaceSymbolProvider": true,
+		"documentSymbolProvider":  true,
+		"definitionProvider":      true,
+		"referencesProvider":      true,
+	}
+	if slices.Contains(params.Capabilities.General.PositionEncodings, "utf-8") {
+		capabilities["positionEncoding"] = "utf-8"
+		s.utf8 = true
+	}
+
+	return map[string]any{
+		"capabilities": capabilities,
+		"serverInfo":   map[string]string{"name": "exarch"},
+	}, nil
+}
+
+// openDocument indexes the unsaved contents of a file open in the editor
+func (s *lspServer) openDocument(uri string, contents []byte) error {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return err
+	}
+
+	s.open[path] = contents
+	return s.watcher.updateContents(path, contents)
+}
+
+// refresh indexes files that changed on disk since the last request. The unsaved contents
+// of open files stay in the watcher's overlay.
+func (s *lspServer) refresh() error {
+	if s.changes != nil {
+		select {
+		case err := <-s.errs:
+			s.conn.notify("window/logMessage", map[string]any{"type": 2, "message": fmt.Sprintf("can't watch for changes, checking every request: %v", err)})
+			s.changes = nil
+		case <-s.changes:
+		default:
+			return nil
+		}
+	}
+
+	_, err := s.watcher.update()
+	return err
+}
+
+// parse returns the tree of a file, using its unsaved contents when it's open
+func (s *lspServer) parse(path string) (*sitter.Node, []byte, error) {
+	contents, ok := s.open[path]
+	if !ok {
+		return parseFile(path)
+	}
+
+	root, err := sitter.ParseCtx(context.Background(), contents, elixir.GetLanguage())
+	return root, contents, err
+}
+
+func (s *lspServer) workspaceSymbols(query string) ([]lspSymbolInformation, error) {
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	symbols := []lspSymbolInformation{}
+	for _, file := range s.watcher.indexedFiles() {
+		for _, module := range file.Modules {
+			if strings.Contains(strings.ToLower(module.Name), query) {
+				symbols = append(symbols, lspSymbolInformation{
+					Name:     module.Name,
+					Kind:     lspSymbolModule,
+					Location: s.locationAt(file.Path, module.Line, module.Column, module.Column),
+				})
+			}
+		}
+
+		seen := map[string]bool{}
+		for _, def := range file.Defs {
+			key := def.Module + "." + def.Signature()
+			if seen[key] || !strings.Contains(strings.ToLower(def.Module+"."+def.Name), query) {
+				continue
+			}
+			seen[key] = true
+
+			symbols = append(symbols, lspSymbolInformation{
+				Name:          def.Signature(),
+				Kind:          lspSymbolFunction,
+				Location:      s.locationAt(file.Path, def.Line, def.Column, def.Column),
+				ContainerName: def.Module,
+			})
+		}
+	}
+
+	return symbols, nil
+}
+
+func (s *lspServer) documentSymbols(uri string) ([]lspDocumentSymbol, error) {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	root, contents, err := s.parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	outline, err := parseOutline(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.lspDocumentSymbols(path, outline), nil
+}
+
+func (s *lspServer) lspDocumentSymbols(path string, symbols []Symbol) []lspDocumentSymbol {
+	documentSymbols := []lspDocumentSymbol{}
+	for _, symbol := range symbols {
+		kind := lspSymbolFunction
+		switch symbol.Kind {
+		case "defmodule":
+			kind = lspSymbolModule
+		case "defstruct", "defexception":
+			kind = lspSymbolStruct
+		}
+
+		symbolRange := lspRange{
+			Start: lspPosition{Line: symbol.Line, Character: s.lspColumn(path, symbol.Line, symbol.Column)},
+			End:   lspPosition{Line: symbol.EndLine, Character: s.lspColumn(path, symbol.EndLine, symbol.EndColumn)},
+		}
+
+		documentSymbols = append(documentSymbols, lspDocumentSymbol{
+			Name:           symbol.Name,
+			Detail:         symbol.Detail,
+			Kind:           kind,
+			Range:          symbolRange,
+			SelectionRange: symbolRange,
+			Children:       s.lspDocumentSymbols(path, symbol.Children),
+		})
+	}
+
+	return documentSymbols
+}
+
+func (s *lspServer) definition(params lspPositionParams) ([]lspLocation, error) {
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	path, err := uriToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	root, contents, err := s.parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing being found isn't an error for the client
+	column := s.byteColumn(path, params.Position.Line, params.Position.Character)
+	locations, err := definitionAt(s.watcher.indexedFiles(), path, root, contents, params.Position.Line+1, column+1)
+	if err != nil {
+		return []lspLocation{}, nil
+	}
+
+	lspLocations := []lspLocation{}
+	for _, location := range locations {
+		lspLocations = append(lspLocations, s.locationAt(location.Path, location.Line-1, location.Column-1, location.Column-1))
+	}
+
+	return lspLocations, nil
+}
+
+func (s *lspServer) references(params lspPositionParams) ([]lspLocation, error) {
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	path, err := uriToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	file := s.watcher.file(path)
+	if file == nil {
+		return []lspLocation{}, nil
+	}
+
+	root, contents, err := s.parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	point := sitter.Point{Row: params.Position.Line, Column: s.byteColumn(path, params.Position.Line, params.Position.Character)}
+	ref, ok := functionAt(file, root, contents, point)
+	if !ok {
+		return []lspLocation{}, nil
+	}
+
+	files := s.watcher.indexedFiles()
+	locations := []lspLocation{}
+	if params.Context.IncludeDeclaration {
+		for _, location := range functionLocations(files, ref) {
+			locations = append(locations, s.locationAt(location.Path, location.Line-1, location.Column-1, location.Column-1))
+		}
+	}
+
+	for _, file := range files {
+		for _, r := range file.Refs {
+			if r.Module == ref.Module && r.Name == ref.Name && sameFunction(files, ref, r.Arity) {
+				end := r.Column + uint32(len(r.Contents))
+				locations = append(locations, s.locationAt(file.Path, r.Line, r.Column, end))
+			}
+		}
+	}
+
+	return locations, nil
+}
+
+// sameFunction checks if calling the referenced function with a different arity calls the
+// same function, because of default arguments.
+func sameFunction(files []*FileIndex, ref Ref, arity int) bool {
+	if arity == ref.Arity {
+		return true
+	}
+
+	for _, file := range files {
+		for _, def := range file.Defs {
+			if def.Module == ref.Module && def.Name == ref.Name && def.HasArity(ref.Arity) {
+				return def.HasArity(arity)
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *lspServer) search(params searchParams) ([]lspSearchResult, error) {
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	input, err := params.searchInput(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := searchFiles(input, s.watcher.index())
+	if err != nil {
+		return nil, err
+	}
+
+	searchResults := []lspSearchResult{}
+	for _, res := range results {
+		searchResults = append(searchResults, lspSearchResult{
+			URI:     pathToURI(filepath.Join(s.dir, res.Path)),
+			Path:    res.Path,
+			Matches: res.Matches,
+		})
+	}
+
+	return searchResults, nil
+}
+
+// locationAt is a location on a single 0 based line, with byte columns converted to the
+// client's encoding
+func (s *lspServer) locationAt(path string, line uint32, column uint32, endColumn uint32) lspLocation {
+	return lspLocation{
+		URI: pathToURI(path),
+		Range: lspRange{
+			Start: lspPosition{Line: line, Character: s.lspColumn(path, line, column)},
+			End:   lspPosition{Line: line, Character: s.lspColumn(path, line, endColumn)},
+		},
+	}
+}
+
+// lspColumn converts a byte column on a line of a file to the client's encoding
+func (s *lspServer) lspColumn(path string, line uint32, column uint32) uint32 {
+	if s.utf8 {
+		return column
+	}
+
+	return utf16Column(s.line(path, line), column)
+}
+
+// byteColumn converts a column in the client's encoding on a line of a file to bytes
+func (s *lspServer) byteColumn(path string, line uint32, character uint32) uint32 {
+	if s.utf8 {
+		return character
+	}
+
+	return utf8Column(s.line(path, line), character)
+}
+
+// line returns a line of a file, from its unsaved contents when it's open. Files that
+// can't be read have no lines.
+func (s *lspServer) line(path string, line uint32) string {
+	lines, ok := s.lines[path]
+	if !ok {
+		contents, open := s.open[path]
+		if !open {
+			contents, _ = os.ReadFile(path)
+		}
+
+		lines = strings.Split(string(contents), "\n")
+		s.lines[path] = lines
+	}
+
+	if int(line) >= len(lines) {
+		return ""
+	}
+
+	return lines[line]
+}
+
+// utf16Column converts a byte column on a line to UTF-16 code units. Columns past the end
+// of the line are kept as they are.
+func utf16Column(line string, column uint32) uint32 {
+	if int(column) > len(line) {
+		return column
+	}
+
+	units := uint32(0)
+	for _, r := range line[:column] {
+		units += uint32(utf16.RuneLen(r))
+	}
+
+	return units
+}
+
+// utf8Column converts a column in UTF-16 code units on a line to bytes
+func utf8Column(line string, character uint32) uint32 {
+	units := uint32(0)
+	for i, r := range line {
+		if units >= character {
+			return uint32(i)
+		}
+		units += uint32(utf16.RuneLen(r))
+	}
+
+	return uint32(len(line)) + character - units
+}
+
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	if u.Scheme != "file" {
+		return "", &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unsupported uri %s", uri)}
+	}
+
+	return filepath.FromSlash(u.Path), nil
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
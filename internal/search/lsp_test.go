@@ -0,0 +1,188 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func lspMessage(t *testing.T, id int, method string, params any) string {
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		msg["id"] = id
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Unable to marshal message, %v", err)
+	}
+
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// runLSP sends messages to the server and returns the results of the responses by id
+func runLSP(t *testing.T, dir string, messages ...string) map[int]json.RawMessage {
+	out := &bytes.Buffer{}
+	if err := ServeLSP(dir, strings.NewReader(strings.Join(messages, "")), out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	results := map[int]json.RawMessage{}
+	conn := newRPCConn(out, nil)
+	for {
+		body, err := conn.readMessage()
+		if err != nil {
+			break
+		}
+
+		res := struct {
+			ID     int             `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}{}
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatalf("Unable to unmarshal response, %v", err)
+		}
+		results[res.ID] = res.Result
+	}
+
+	return results
+}
+
+func TestServeLSP(t *testing.T) {
+	dir := writeTestProject(t)
+	users := pathToURI(filepath.Join(dir, "users.ex"))
+	accounts := pathToURI(filepath.Join(dir, "accounts.ex"))
+	position := func(uri string, line int, character int) map[string]any {
+		return map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": line, "character": character},
+			"context":      map[string]any{"includeDeclaration": false},
+		}
+	}
+
+	// accounts.ex is opened with an unsaved blank line at the top
+	opened := "\n" + string(mustRead(t, filepath.Join(dir, "accounts.ex")))
+
+	results := runLSP(t, dir,
+		lspMessage(t, 1, "initialize", map[string]any{"rootUri": pathToURI(dir)}),
+		lspMessage(t, 0, "initialized", map[string]any{}),
+		lspMessage(t, 0, "textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": accounts, "text": opened},
+		}),
+		lspMessage(t, 2, "textDocument/definition", position(accounts, 17, 21)),
+		lspMessage(t, 3, "textDocument/references", position(users, 12, 7)),
+		lspMessage(t, 4, "textDocument/documentSymbol", position(accounts, 0, 0)),
+		lspMessage(t, 5, "workspace/symbol", map[string]any{"query": "admin"}),
+		lspMessage(t, 6, "exarch/search", map[string]any{"mode": "str", "query": "Hello"}),
+		lspMessage(t, 7, "unknown/method", map[string]any{}),
+		lspMessage(t, 8, "shutdown", nil),
+		lspMessage(t, 0, "exit", nil),
+	)
+
+	locations := []lspLocation{}
+	json.Unmarshal(results[2], &locations)
+	if len(locations) != 1 || locations[0].URI != users || locations[0].Range.Start != (lspPosition{Line: 12, Character: 2}) {
+		t.Errorf("got definition %+v", locations)
+	}
+
+	locations = []lspLocation{}
+	json.Unmarshal(results[3], &locations)
+	lines := []uint32{}
+	for _, location := range locations {
+		if location.URI != accounts {
+			t.Errorf("got reference in %s", location.URI)
+		}
+		lines = append(lines, location.Range.Start.Line)
+	}
+	if !reflect.DeepEqual(lines, []uint32{4, 6, 10, 17}) {
+		t.Errorf("got references on lines %v want [4 6 10 17]", lines)
+	}
+
+	symbols := []lspDocumentSymbol{}
+	json.Unmarshal(results[4], &symbols)
+	if len(symbols) != 2 || symbols[0].Name != "TestApp.Accounts" || len(symbols[0].Children) != 3 || symbols[1].Range.Start.Line != 14 {
+		t.Errorf("got document symbols %+v", symbols)
+	}
+
+	workspaceSymbols := []lspSymbolInformation{}
+	json.Unmarshal(results[5], &workspaceSymbols)
+	names := []string{}
+	for _, symbol := range workspaceSymbols {
+		names = append(names, symbol.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"TestApp.Accounts.Admin", "fetch/1"}) {
+		t.Errorf("got workspace symbols %v", names)
+	}
+
+	searchResults := []lspSearchResult{}
+	json.Unmarshal(results[6], &searchResults)
+	if len(searchResults) != 1 || searchResults[0].Path != "users.ex" || len(searchResults[0].Matches) != 1 {
+		t.Errorf("got search results %+v", searchResults)
+	}
+
+	if results[7] != nil {
+		t.Errorf("expected unknown methods to fail, got %s", results[7])
+	}
+}
+
+func TestServeLSPPositionEncoding(t *testing.T) {
+	dir := writeTestProject(t)
+	greeter := filepath.Join(dir, "greeter.ex")
+	source := "defmodule TestApp.Greeter do\n  def greet(id), do: \"héllo 😀\" <> TestApp.Accounts.Users.get_user!(id)\nend\n"
+	addProjectFiles(t, dir, map[string]string{"greeter.ex": source})
+
+	line := strings.Split(source, "\n")[1]
+	byteColumn := strings.Index(line, "TestApp")
+	utf16Column := byteColumn - 3 // é is 2 bytes and 1 unit, 😀 is 4 bytes and 2 units
+
+	tests := []struct {
+		encodings []string
+		column    int
+	}{
+		{nil, utf16Column},
+		{[]string{"utf-16"}, utf16Column},
+		{[]string{"utf-8", "utf-16"}, byteColumn},
+	}
+
+	for _, test := range tests {
+		position := map[string]any{
+			"textDocument": map[string]any{"uri": pathToURI(greeter)},
+			"position":     map[string]any{"line": 1, "character": test.column + len("TestApp.Accounts.Users.")},
+			"context":      map[string]any{"includeDeclaration": false},
+		}
+		initialize := map[string]any{
+			"rootUri":      pathToURI(dir),
+			"capabilities": map[string]any{"general": map[string]any{"positionEncodings": test.encodings}},
+		}
+
+		results := runLSP(t, dir,
+			lspMessage(t, 1, "initialize", initialize),
+			lspMessage(t, 2, "textDocument/definition", position),
+			lspMessage(t, 3, "textDocument/references", position),
+			lspMessage(t, 0, "exit", nil),
+		)
+
+		locations := []lspLocation{}
+		json.Unmarshal(results[2], &locations)
+		if len(locations) != 1 || locations[0].Range.Start != (lspPosition{Line: 12, Character: 2}) {
+			t.Errorf("%v: got definition %+v", test.encodings, locations)
+		}
+
+		locations = []lspLocation{}
+		json.Unmarshal(results[3], &locations)
+		found := false
+		for _, location := range locations {
+			if location.URI == pathToURI(greeter) {
+				found = location.Range.Start == lspPosition{Line: 1, Character: uint32(test.column)}
+			}
+		}
+		if !found {
+			t.Errorf("%v: got references %+v want one at column %d of greeter.ex", test.encodings, locations, test.column)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+type Module struct {
+	Name   string
+	Line   uint32
+	Column uint32
+	node   *sitter.Node
+}
+
+//go:embed queries/module_def.scm
+var moduleDefQuery string
+
+// Generate a list of all modules defined in the file. Nested modules are given their
+// fully qualified name, so defmodule Child inside of defmodule Parent becomes Parent.Child.
+func parseModules(root *sitter.Node, contents []byte) ([]Module, error) {
+	query, err := newQuery(moduleDefQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	modules := []Module{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var node, nameNode *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "module":
+				node = capture.Node
+			case "module_name":
+				nameNode = capture.Node
+			}
+		}
+
+		if node == nil || nameNode == nil {
+			continue
+		}
+
+		// matches come back in document order, so any parent module has already been seen
+		name := nameNode.Content(contents)
+		if parent := enclosingModule(node.Parent(), modules); parent != nil {
+			name = fmt.Sprintf("%s.%s", parent.Name, name)
+		}
+
+		modules = append(modules, Module{
+			Name:   name,
+			Line:   node.StartPoint().Row,
+			Column: node.StartPoint().Column,
+			node:   node,
+		})
+	}
+
+	return modules, nil
+}
+
+// find the innermost module that contains the node
+func enclosingModule(node *sitter.Node, modules []Module) *Module {
+	if node == nil {
+		return nil
+	}
+
+	var found *Module
+	for i, module := range modules {
+		if containsNode(module.node, node) {
+			if found == nil || module.node.StartByte() >= found.node.StartByte() {
+				found = &modules[i]
+			}
+		}
+	}
+
+	return found
+}
+
+// check if the inner node is inside of the outer node
+func containsNode(outer *sitter.Node, inner *sitter.Node) bool {
+	return outer.StartByte() <= inner.StartByte() && outer.EndByte() >= inner.EndByte()
+}
+
+// moduleBody returns the direct children of the module's do block.
+func moduleBody(module Module) []*sitter.Node {
+	body := []*sitter.Node{}
+	for i := range int(module.node.NamedChildCount()) {
+		if block := module.node.NamedChild(i); block.Type() == "do_block" {
+			for j := range int(block.NamedChildCount()) {
+				body = append(body, block.NamedChild(j))
+			}
+		}
+	}
+
+	return body
+}
+
+// matchesModule checks if a fully qualified module path is the given module name, or
+// ends with it. Eg. TestApp.Worker matches both TestApp.Worker and Worker.
+func matchesModule(modulePath string, name string) bool {
+	return modulePath == name || strings.HasSuffix(modulePath, "."+name)
+}
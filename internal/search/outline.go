@@ -0,0 +1,129 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Symbol is a module, function or struct in the outline of a file. A function with
+// several clauses is a single symbol spanning all of them.
+type Symbol struct {
+	Name      string   `json:"name"`             // The module name, or the function in name/arity form
+	Kind      string   `json:"kind"`             // defmodule, defstruct, defexception or the keyword defining the function
+	Detail    string   `json:"detail,omitempty"` // The head of the first clause of a function
+	Line      uint32   `json:"line"`
+	Column    uint32   `json:"column"`
+	EndLine   uint32   `json:"endLine"`
+	EndColumn uint32   `json:"endColumn"`
+	Children  []Symbol `json:"children,omitempty"`
+}
+
+func (s Symbol) Format() string {
+	lines := []string{fmt.Sprintf("%d:%s %s", s.Line, s.Kind, s.Name)}
+	for _, child := range s.Children {
+		lines = append(lines, "  "+strings.ReplaceAll(child.Format(), "\n", "\n  "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Generate the outline of a file. Modules contain their functions, structs and nested
+// modules, and functions defined outside of a module are at the top level.
+func parseOutline(root *sitter.Node, contents []byte) ([]Symbol, error) {
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := parseFuncDefs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	structs, err := parseStructs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]Symbol, len(modules))
+	moduleIndex := map[string]int{}
+	for i, module := range modules {
+		symbols[i] = nodeSymbol(module.Name, "defmodule", module.node)
+		moduleIndex[module.Name] = i
+	}
+
+	topLevel := []Symbol{}
+	addChild := func(module string, symbol Symbol) {
+		if i, ok := moduleIndex[module]; ok {
+			symbols[i].Children = append(symbols[i].Children, symbol)
+		} else {
+			topLevel = append(topLevel, symbol)
+		}
+	}
+
+	// clauses after the first extend the function's symbol
+	functions := map[string]*Symbol{}
+	for _, def := range defs {
+		key := def.Module + "." + def.Signature()
+		if function := functions[key]; function != nil {
+			function.EndLine = def.node.EndPoint().Row
+			function.EndColumn = def.node.EndPoint().Column
+			continue
+		}
+
+		symbol := nodeSymbol(def.Signature(), def.Kind, def.node)
+		symbol.Detail = def.Contents
+		functions[key] = &symbol
+	}
+
+	for _, def := range defs {
+		key := def.Module + "." + def.Signature()
+		if function := functions[key]; function != nil {
+			addChild(def.Module, *function)
+			delete(functions, key)
+		}
+	}
+
+	for _, s := range structs {
+		kind := s.node.ChildByFieldName("target").Content(contents)
+		addChild(s.Module, nodeSymbol(s.Module, kind, s.node))
+	}
+
+	// nested modules come after their parent, so working backwards means every nested
+	// module is complete before it's added to its parent
+	for i := len(modules) - 1; i >= 0; i-- {
+		sortSymbols(symbols[i].Children)
+
+		if parent := enclosingModule(modules[i].node.Parent(), modules); parent != nil {
+			addChild(parent.Name, symbols[i])
+		} else {
+			topLevel = append(topLevel, symbols[i])
+		}
+	}
+
+	sortSymbols(topLevel)
+	return topLevel, nil
+}
+
+func nodeSymbol(name string, kind string, node *sitter.Node) Symbol {
+	return Symbol{
+		Name:      name,
+		Kind:      kind,
+		Line:      node.StartPoint().Row,
+		Column:    node.StartPoint().Column,
+		EndLine:   node.EndPoint().Row,
+		EndColumn: node.EndPoint().Column,
+	}
+}
+
+func sortSymbols(symbols []Symbol) {
+	sort.SliceStable(symbols, func(i, j int) bool {
+		if symbols[i].Line != symbols[j].Line {
+			return symbols[i].Line < symbols[j].Line
+		}
+		return symbols[i].Column < symbols[j].Column
+	})
+}
@@ -0,0 +1,33 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOutline(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/shapes.ex")
+	symbols, err := parseOutline(root, contents)
+	if err != nil {
+		t.Errorf("parse outline failed: %v", err)
+	}
+
+	expected := []Symbol{{
+		Name: "TestApp.Shapes", Kind: "defmodule", Line: 0, Column: 0, EndLine: 14, EndColumn: 3,
+		Children: []Symbol{
+			{
+				Name: "TestApp.Shapes.Circle", Kind: "defmodule", Line: 1, Column: 2, EndLine: 5, EndColumn: 5,
+				Children: []Symbol{
+					{Name: "TestApp.Shapes.Circle", Kind: "defstruct", Line: 2, Column: 4, EndLine: 2, EndColumn: 23},
+					{Name: "area/1", Kind: "def", Detail: "area(%Circle{radius: r})", Line: 4, Column: 4, EndLine: 4, EndColumn: 50},
+				},
+			},
+			{Name: "describe/1", Kind: "def", Detail: "describe(%Circle{})", Line: 7, Column: 2, EndLine: 11, EndColumn: 5},
+			{Name: "log/1", Kind: "defp", Detail: "log(message)", Line: 13, Column: 2, EndLine: 13, EndColumn: 41},
+		},
+	}}
+
+	if !reflect.DeepEqual(symbols, expected) {
+		t.Errorf("got %+v want %+v", symbols, expected)
+	}
+}
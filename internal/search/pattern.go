@@ -0,0 +1,282 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/elixir"
+)
+
+// metavariables like $NAME aren't valid elixir, so before a pattern is parsed they are
+// replaced with identifiers starting with this prefix.
+const metavarPrefix = "__exarch_"
+
+var metavarRegex = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// nodes whose children are a sequence of expressions or clauses. A pattern only needs
+// to match some of them, in order, so `case $X do {:ok, $V} -> $_ end` matches a case
+// with other clauses too.
+var patternBlocks = []string{"source", "block", "body", "do_block", "else_block", "after_block", "rescue_block", "catch_block"}
+
+// PatternMatch is code matching a pattern, along with the values of its metavariables.
+type PatternMatch struct {
+	Line     uint32
+	Contents string // The first line of the matched code
+	Bindings []Binding
+
+	node *sitter.Node
+}
+
+func (p PatternMatch) Format() string {
+	if len(p.Bindings) == 0 {
+		return fmt.Sprintf("%d:%s", p.Line, p.Contents)
+	}
+
+	bindings := []string{}
+	for _, binding := range p.Bindings {
+		bindings = append(bindings, fmt.Sprintf("$%s=%s", binding.Name, binding.Value))
+	}
+
+	return fmt.Sprintf("%d:%s [%s]", p.Line, p.Contents, strings.Join(bindings, " "))
+}
+
+// Binding is the code a metavariable matched
+type Binding struct {
+	Name  string // The metavariable name without the $
+	Value string // The first line of the matched code
+
+	node *sitter.Node
+}
+
+// codePattern is a parsed pattern. $_ matches anything, any other metavariable matches
+// anything but has to match the same code everywhere it is used.
+type codePattern struct {
+	root     *sitter.Node
+	contents []byte
+	names    []string // metavariable names in the order they first appear
+	query    *sitter.Query
+}
+
+// compilePattern parses a pattern written as a single elixir expression.
+func compilePattern(source string) (*codePattern, error) {
+	names := []string{}
+	for _, match := range metavarRegex.FindAllStringSubmatch(source, -1) {
+		if match[1] != "_" && !slices.Contains(names, match[1]) {
+			names = append(names, match[1])
+		}
+	}
+
+	contents := []byte(metavarRegex.ReplaceAllString(source, metavarPrefix+"$1"))
+	tree, err := sitter.ParseCtx(context.Background(), contents, elixir.GetLanguage())
+	if err != nil {
+		return nil, err
+	}
+
+	if tree.HasError() {
+		return nil, fmt.Errorf("invalid pattern: %s isn't valid elixir", source)
+	}
+
+	exprs := namedChildren(tree)
+	if len(exprs) != 1 {
+		return nil, fmt.Errorf("invalid pattern: give a single expression")
+	}
+
+	pattern := &codePattern{root: exprs[0], contents: contents, names: names}
+	if _, ok := pattern.metavar(pattern.root); ok {
+		return nil, fmt.Errorf("invalid pattern: a metavariable on its own matches everything")
+	}
+
+	// only nodes with the same type as the pattern need to be compared with it
+	query := fmt.Sprintf("(%s) @match", pattern.root.Type())
+	pattern.query, err = sitter.NewQuery([]byte(query), elixir.GetLanguage())
+	if err != nil {
+		return nil, err
+	}
+
+	return pattern, nil
+}
+
+// metavar returns the name of the metavariable a pattern node is, if it is one.
+func (p *codePattern) metavar(node *sitter.Node) (string, bool) {
+	if node.Type() != "identifier" {
+		return "", false
+	}
+
+	return strings.CutPrefix(node.Content(p.contents), metavarPrefix)
+}
+
+// patternMatcher compares a pattern with the code of a file
+type patternMatcher struct {
+	pattern  *codePattern
+	contents []byte
+	aliases  []Alias
+}
+
+// findPatternMatches returns the code in a file matching the pattern, in file order.
+func findPatternMatches(root *sitter.Node, contents []byte, pattern *codePattern) ([]PatternMatch, error) {
+	aliases, err := parseAliases(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := &patternMatcher{pattern: pattern, contents: contents, aliases: aliases}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(pattern.query, root)
+
+	matches := []PatternMatch{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, capture := range match.Captures {
+			bound := map[string]*sitter.Node{}
+			if !matcher.match(pattern.root, capture.Node, bound) {
+				continue
+			}
+
+			bindings := []Binding{}
+			for _, name := range pattern.names {
+				if node, ok := bound[name]; ok {
+					value := strings.SplitN(node.Content(contents), "\n", 2)[0]
+					bindings = append(bindings, Binding{Name: name, Value: value, node: node})
+				}
+			}
+
+			matches = append(matches, PatternMatch{
+				Line:     capture.Node.StartPoint().Row,
+				Contents: strings.SplitN(capture.Node.Content(contents), "\n", 2)[0],
+				Bindings: bindings,
+				node:     capture.Node,
+			})
+		}
+	}
+
+	return matches, nil
+}
+
+// match compares a pattern node with a node of the file, binding metavariables as it goes.
+func (m *patternMatcher) match(pattern *sitter.Node, node *sitter.Node, bound map[string]*sitter.Node) bool {
+	if name, ok := m.pattern.metavar(pattern); ok {
+		if name == "_" {
+			return true
+		}
+
+		// a metavariable used more than once has to match the same code each time
+		if prev, ok := bound[name]; ok {
+			return normalizeSpace(prev.Content(m.contents)) == normalizeSpace(node.Content(m.contents))
+		}
+
+		bound[name] = node
+		return true
+	}
+
+	if pattern.Type() != node.Type() {
+		return false
+	}
+
+	// modules are compared after resolving the aliases of the file, so Repo matches
+	// MyApp.Repo when it has been aliased
+	if pattern.Type() == "alias" {
+		return matchesModule(findFullModulePath(node.Content(m.contents), m.aliases), pattern.Content(m.pattern.contents))
+	}
+
+	// the operator of binary and unary operators is an unnamed node
+	if operator := pattern.ChildByFieldName("operator"); operator != nil {
+		nodeOperator := node.ChildByFieldName("operator")
+		if nodeOperator == nil || operator.Type() != nodeOperator.Type() {
+			return false
+		}
+	}
+
+	patternChildren := namedChildren(pattern)
+	nodeChildren := namedChildren(node)
+	if len(patternChildren) == 0 && len(nodeChildren) == 0 {
+		return normalizeSpace(pattern.Content(m.pattern.contents)) == normalizeSpace(node.Content(m.contents))
+	}
+
+	if slices.Contains(patternBlocks, pattern.Type()) {
+		return m.matchSubsequence(patternChildren, nodeChildren, bound)
+	}
+
+	if len(patternChildren) != len(nodeChildren) {
+		return false
+	}
+
+	for i := range patternChildren {
+		if !m.match(patternChildren[i], nodeChildren[i], bound) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchSubsequence matches each pattern node with one of the nodes, in order, skipping
+// nodes that don't match. Bindings are only kept when everything matches.
+func (m *patternMatcher) matchSubsequence(patterns []*sitter.Node, nodes []*sitter.Node, bound map[string]*sitter.Node) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for i, node := range nodes {
+		attempt := maps.Clone(bound)
+		if m.match(patterns[0], node, attempt) && m.matchSubsequence(patterns[1:], nodes[i+1:], attempt) {
+			maps.Copy(bound, attempt)
+			return true
+		}
+	}
+
+	return false
+}
+
+// namedChildren returns the named children of a node, leaving out comments.
+func namedChildren(node *sitter.Node) []*sitter.Node {
+	children := []*sitter.Node{}
+	for i := range int(node.NamedChildCount()) {
+		child := node.NamedChild(i)
+		if child.Type() != "comment" {
+			children = append(children, child)
+		}
+	}
+
+	return children
+}
+
+// normalizeSpace collapses whitespace so code formatted differently compares equal
+func normalizeSpace(code string) string {
+	return strings.Join(strings.Fields(code), " ")
+}
+
+// searchPattern finds code matching the pattern in the search terms, eg.
+// Repo.get!($SCHEMA, $ID).
+func searchPattern(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	pattern := input.pattern
+	if pattern == nil {
+		var err error
+		pattern, err = compilePattern(input.SearchTerms)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matches, err := findPatternMatches(root, contents, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []ResultsFormatter{}
+	for _, match := range matches {
+		results = append(results, match)
+	}
+
+	return results, nil
+}
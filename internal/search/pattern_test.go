@@ -0,0 +1,62 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchPattern(t *testing.T) {
+	root, contents := readTestFile(t)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{
+			"Repo.get!($SCHEMA, $ID)",
+			[]string{"12:Repo.get!(User, id) [$SCHEMA=User $ID=id]"},
+		},
+		// modules are compared after resolving aliases
+		{
+			"TestApp.Repo.get_by($_, username: $NAME)",
+			[]string{"15:Repo.get_by(User, username: username) [$NAME=username]"},
+		},
+		{"Other.Repo.get!($_, $_)", []string{}},
+		// clauses that aren't in the pattern are skipped
+		{
+			"case $X do :lime -> $COLOR end",
+			[]string{`30:case user.favorite_fruit do [$X=user.favorite_fruit $COLOR="green"]`},
+		},
+		// a metavariable used twice has to match the same code
+		{"Repo.get!($X, $X)", []string{}},
+		{
+			"$USER |> $FN()",
+			[]string{"19:user [$USER=user $FN=Repo.update]", "19:user [$USER=user $FN=TestApp.FilterChain.process]"},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := searchPattern(root, contents, &SearchInput{SearchTerms: test.pattern})
+		if err != nil {
+			t.Errorf("search pattern failed: %v", err)
+		}
+
+		got := []string{}
+		for _, result := range results {
+			got = append(got, result.Format())
+		}
+
+		if strings.Join(got, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.pattern, strings.Join(got, "\n"), strings.Join(test.expected, "\n"))
+		}
+	}
+}
+
+func TestSearchPatternInvalid(t *testing.T) {
+	root, contents := readTestFile(t)
+	for _, pattern := range []string{"Repo.get!($X", "$X", "foo()\nbar()"} {
+		if _, err := searchPattern(root, contents, &SearchInput{SearchTerms: pattern}); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+			t.Errorf("%s: got %v want an invalid pattern error", pattern, err)
+		}
+	}
+}
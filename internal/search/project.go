@@ -0,0 +1,175 @@
+package search
+
+import (
+	"context"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/elixir"
+)
+
+// FileIndex holds everything project wide commands need to know about a single file.
+type FileIndex struct {
+	Path    string
+	Modules []Module
+	Defs    []FuncDef
+	Impls   []string // Module.function/arity of every definition marked with @impl or in a defimpl
+	Aliases []Alias
+	Imports []Import
+	Refs    []Ref
+	Structs []Struct
+	Deps    []ModuleDep
+	Calls   []FnCall // Remote calls as found by fncall searches
+	Strings []string // The contents of every string, sigil and charlist
+
+	// used to check if the file has changed since it was indexed
+	Hash    string
+	ModTime int64
+	Size    int64
+}
+
+// indexFile parses the contents of a file and collects its modules, definitions, callbacks,
+// aliases, imports, references, structs, module dependencies, calls and strings.
+func indexFile(path string, contents []byte) (*FileIndex, error) {
+	root, err := sitter.ParseCtx(context.Background(), contents, elixir.GetLanguage())
+	if err != nil {
+		return nil, err
+	}
+
+	return indexTree(path, root, contents)
+}
+
+// indexTree is indexFile for a file that has already been parsed.
+func indexTree(path string, root *sitter.Node, contents []byte) (*FileIndex, error) {
+	aliases, err := parseAliases(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := parseFuncDefs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	imports, err := parseImports(root, contents, modules, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := parseImplAttrs(root, contents, defs, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	impls := []string{}
+	for _, attr := range attrs {
+		impls = append(impls, attr.Module+"."+attr.Def.Signature())
+	}
+
+	// functions in a defimpl implement the protocol's callbacks
+	for _, def := range defs {
+		if insideCall(def.node, contents, "defimpl") {
+			impls = append(impls, def.Module+"."+def.Signature())
+		}
+	}
+
+	refs, err := parseRefs(root, contents, modules, defs, aliases, imports)
+	if err != nil {
+		return nil, err
+	}
+
+	structs, err := parseStructs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	deps, err := parseModuleDeps(root, contents, modules, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	calls, err := parseRemoteCalls(root, contents, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	strs, err := parseStrings(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileIndex{
+		Path:    path,
+		Modules: modules,
+		Defs:    defs,
+		Impls:   impls,
+		Aliases: aliases,
+		Imports: imports,
+		Refs:    refs,
+		Structs: structs,
+		Deps:    deps,
+		Calls:   calls,
+		Strings: strs,
+	}, nil
+}
+
+// indexProject indexes every elixir file under dir and resolves calls to imported
+// functions. When the project has an on disk index only files that changed since the
+// last run are parsed, and the index is updated with them.
+func indexProject(dir string) ([]*FileIndex, error) {
+	index, err := loadIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, reparsed, err := refreshIndex(dir, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if index != nil && reparsed > 0 {
+		if err := saveIndex(dir, files); err != nil {
+			return nil, err
+		}
+	}
+
+	resolveImports(files)
+	return files, nil
+}
+
+// resolve local calls that could have come from an import by checking which of the
+// imported modules defines the function. The modules a call could have been imported from
+// are kept, so calls are resolved again when files are re-indexed.
+func resolveImports(files []*FileIndex) {
+	defined := map[string][]FuncDef{}
+	for _, file := range files {
+		for _, def := range file.Defs {
+			if def.Kind != "defp" && def.Kind != "defmacrop" && def.Kind != "defguardp" {
+				defined[def.Module] = append(defined[def.Module], def)
+			}
+		}
+	}
+
+	for _, file := range files {
+		for i, ref := range file.Refs {
+			if len(ref.Imports) == 0 {
+				continue
+			}
+
+			file.Refs[i].Module = ""
+			file.Refs[i].Kind = "call"
+			for _, imported := range ref.Imports {
+				for _, def := range defined[imported] {
+					if def.Name == ref.Name && def.HasArity(ref.Arity) {
+						file.Refs[i].Module = imported
+						file.Refs[i].Kind = "import"
+					}
+				}
+			}
+		}
+	}
+}
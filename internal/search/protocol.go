@@ -0,0 +1,267 @@
+package search
+
+import (
+	"cmp"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Protocol is a protocol definition (defprotocol) or an implementation of one
+// (defimpl or @derive). For holds the types a protocol is implemented for.
+type Protocol struct {
+	Protocol  string
+	For       []string
+	Kind      string
+	Line      uint32
+	Contents  string
+	Functions []string
+}
+
+func (p Protocol) Format() string {
+	lines := []string{fmt.Sprintf("%d:%s", p.Line, p.Contents)}
+	for _, fn := range p.Functions {
+		lines = append(lines, "  "+fn)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+//go:embed queries/protocol.scm
+var protocolQuery string
+
+//go:embed queries/derive.scm
+var deriveQuery string
+
+// Generate a list of all defprotocol and defimpl blocks along with the functions they
+// define.
+func parseProtocols(root *sitter.Node, contents []byte, modules []Module, defs []FuncDef, aliases []Alias) ([]Protocol, error) {
+	query, err := newQuery(protocolQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	protocols := []Protocol{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var definition, keyword, protocol *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "definition":
+				definition = capture.Node
+			case "keyword":
+				keyword = capture.Node
+			case "protocol":
+				protocol = capture.Node
+			}
+		}
+
+		if definition == nil || keyword == nil || protocol == nil {
+			continue
+		}
+
+		p := Protocol{
+			Kind:     keyword.Content(contents),
+			Line:     definition.StartPoint().Row,
+			Contents: strings.TrimSuffix(strings.SplitN(definition.Content(contents), "\n", 2)[0], " do"),
+		}
+
+		if p.Kind == "defprotocol" {
+			p.Protocol = protocolName(protocol.Content(contents), definition, modules)
+		} else {
+			p.Protocol = findFullModulePath(protocol.Content(contents), aliases)
+			p.For = implTargets(protocol.Parent(), contents, aliases)
+
+			// defimpl without for: inside of a module implements the protocol for that module
+			if len(p.For) == 0 {
+				if module := enclosingModule(definition, modules); module != nil {
+					p.For = []string{module.Name}
+				}
+			}
+		}
+
+		for _, def := range defs {
+			if containsNode(definition, def.node) {
+				p.Functions = append(p.Functions, fmt.Sprintf("%d:%s", def.Line, def.Signature()))
+			}
+		}
+
+		protocols = append(protocols, p)
+	}
+
+	return protocols, nil
+}
+
+// protocols defined inside of a module are namespaced by it like nested modules
+func protocolName(name string, definition *sitter.Node, modules []Module) string {
+	if parent := enclosingModule(definition, modules); parent != nil {
+		return fmt.Sprintf("%s.%s", parent.Name, name)
+	}
+
+	return name
+}
+
+// get the modules from the for: option of a defimpl, which can be a single module or a
+// list of them.
+func implTargets(arguments *sitter.Node, contents []byte, aliases []Alias) []string {
+	targets := []string{}
+	for i := range int(arguments.NamedChildCount()) {
+		keywords := arguments.NamedChild(i)
+		if keywords.Type() != "keywords" {
+			continue
+		}
+
+		for j := range int(keywords.NamedChildCount()) {
+			pair := keywords.NamedChild(j)
+			key := pair.ChildByFieldName("key")
+			if key == nil || strings.TrimSpace(key.Content(contents)) != "for:" {
+				continue
+			}
+
+			value := pair.ChildByFieldName("value")
+			switch value.Type() {
+			case "alias":
+				targets = append(targets, findFullModulePath(value.Content(contents), aliases))
+			case "list":
+				for k := range int(value.NamedChildCount()) {
+					if target := value.NamedChild(k); target.Type() == "alias" {
+						targets = append(targets, findFullModulePath(target.Content(contents), aliases))
+					}
+				}
+			}
+		}
+	}
+
+	return targets
+}
+
+// Generate a list of protocols derived with @derive. Each derived protocol is
+// implemented for the enclosing module.
+func parseDerives(root *sitter.Node, contents []byte, modules []Module, aliases []Alias) ([]Protocol, error) {
+	query, err := newQuery(deriveQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	protocols := []Protocol{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var derive, arguments *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "derive":
+				derive = capture.Node
+			case "protocols":
+				arguments = capture.Node
+			}
+		}
+
+		if derive == nil || arguments == nil {
+			continue
+		}
+
+		module := enclosingModule(derive, modules)
+		if module == nil {
+			continue
+		}
+
+		// @derive accepts a protocol, a {protocol, opts} tuple, or a list of either. Each
+		// is reported on its own so the output shows which protocol matched.
+		for _, name := range derivedProtocols(arguments, contents) {
+			protocols = append(protocols, Protocol{
+				Protocol: findFullModulePath(name, aliases),
+				For:      []string{module.Name},
+				Kind:     "@derive",
+				Line:     derive.StartPoint().Row,
+				Contents: "@derive " + name,
+			})
+		}
+	}
+
+	return protocols, nil
+}
+
+func derivedProtocols(node *sitter.Node, contents []byte) []string {
+	names := []string{}
+	for i := range int(node.NamedChildCount()) {
+		child := node.NamedChild(i)
+		switch child.Type() {
+		case "alias":
+			names = append(names, child.Content(contents))
+		case "tuple":
+			if first := child.NamedChild(0); first != nil && first.Type() == "alias" {
+				names = append(names, first.Content(contents))
+			}
+		case "list":
+			names = append(names, derivedProtocols(child, contents)...)
+		}
+	}
+
+	return names
+}
+
+func searchProtocols(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	aliases, err := parseAliases(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	defs, err := parseFuncDefs(root, contents, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	protocols, err := parseProtocols(root, contents, modules, defs, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	derives, err := parseDerives(root, contents, modules, aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	all := append(protocols, derives...)
+	slices.SortStableFunc(all, func(a, b Protocol) int { return cmp.Compare(a.Line, b.Line) })
+
+	// match on either the protocol name or any of the types it's implemented for
+	matching := []ResultsFormatter{}
+	for _, p := range all {
+		matches := matchesModule(p.Protocol, input.SearchTerms)
+		for _, target := range p.For {
+			matches = matches || matchesModule(target, input.SearchTerms)
+		}
+
+		if matches {
+			matching = append(matching, p)
+		}
+	}
+
+	return matching, nil
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchProtocolsByProtocol(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/sizer.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeProtocol,
+		SearchTerms: "Sizer",
+	}
+
+	matches, err := searchProtocols(root, contents, input)
+	if err != nil {
+		t.Errorf("search protocols failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Protocol{
+			Protocol:  "TestApp.Sizer",
+			Kind:      "defprotocol",
+			Line:      0,
+			Contents:  "defprotocol TestApp.Sizer",
+			Functions: []string{"2:size/1"},
+		},
+		Protocol{
+			Protocol:  "TestApp.Sizer",
+			For:       []string{"Map"},
+			Kind:      "defimpl",
+			Line:      5,
+			Contents:  "defimpl TestApp.Sizer, for: Map",
+			Functions: []string{"6:size/1"},
+		},
+		Protocol{
+			Protocol:  "TestApp.Sizer",
+			For:       []string{"List", "TestApp.Accounts.User"},
+			Kind:      "defimpl",
+			Line:      9,
+			Contents:  "defimpl TestApp.Sizer, for: [List, TestApp.Accounts.User]",
+			Functions: []string{"10:size/1", "11:size/1"},
+		},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %+v want %+v", matches, expected)
+	}
+}
+
+func TestSearchProtocolsByType(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/user.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeProtocol,
+		SearchTerms: "User",
+	}
+
+	matches, err := searchProtocols(root, contents, input)
+	if err != nil {
+		t.Errorf("search protocols failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Protocol{Protocol: "Inspect", For: []string{"TestApp.Accounts.User"}, Kind: "@derive", Line: 1, Contents: "@derive Inspect"},
+		Protocol{Protocol: "Jason.Encoder", For: []string{"TestApp.Accounts.User"}, Kind: "@derive", Line: 1, Contents: "@derive Jason.Encoder"},
+		Protocol{
+			Protocol:  "String.Chars",
+			For:       []string{"TestApp.Accounts.User"},
+			Kind:      "defimpl",
+			Line:      4,
+			Contents:  "defimpl String.Chars",
+			Functions: []string{"5:to_string/1"},
+		},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %+v want %+v", matches, expected)
+	}
+}
@@ -0,0 +1,3 @@
+(call target: (identifier) @keyword
+  (arguments . (alias) @behaviour)
+  (#match? @keyword "^(behaviour|use)$")) @declaration
@@ -0,0 +1,5 @@
+(unary_operator
+  operator: "@"
+  operand: (call target: (identifier) @keyword
+    (arguments (binary_operator) @spec))
+  (#match? @keyword "^(callback|macrocallback)$")) @callback
@@ -0,0 +1 @@
+((comment) @comment)
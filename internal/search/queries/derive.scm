@@ -0,0 +1,5 @@
+(unary_operator
+  operator: "@"
+  operand: (call target: (identifier) @keyword
+    (arguments) @protocols)
+  (#eq? @keyword "derive")) @derive
@@ -0,0 +1,5 @@
+(unary_operator
+  operator: "@"
+  operand: (call target: (identifier) @keyword
+    (arguments . (_) @doc))
+  (#match? @keyword "^(doc|moduledoc|typedoc|shortdoc)$")) @attribute
@@ -0,0 +1,3 @@
+(call target: (identifier) @keyword
+  (arguments . (string) @name)
+  (#match? @keyword "^(test|describe)$")) @block
@@ -0,0 +1,2 @@
+(call target: (identifier) @keyword
+  (#match? @keyword "^(setup|setup_all)$")) @setup
@@ -1,5 +1,8 @@
 (call target: (identifier) @keyword
   (arguments
     [(call target: (identifier))
-     (identifier)] @func_name)) @identifier
-(#match? @keyword "^(def|defp)$")
+     (identifier)
+     (binary_operator
+       left: [(call target: (identifier)) (identifier)]
+       operator: "when")] @func_name)
+  (#match? @keyword "^(def|defp|defmacro|defmacrop|defguard|defguardp|defdelegate)$")) @identifier
@@ -0,0 +1,5 @@
+(unary_operator
+  operator: "@"
+  operand: (call target: (identifier) @keyword
+    (arguments [(boolean) (alias)] @value))
+  (#eq? @keyword "impl")) @impl
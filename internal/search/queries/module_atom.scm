@@ -0,0 +1 @@
+(quoted_atom (quoted_content) @content)
@@ -0,0 +1,3 @@
+(call target: (identifier) @keyword
+  (arguments (alias) @module_name)
+  (#eq? @keyword "defmodule")) @module
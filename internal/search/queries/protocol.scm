@@ -0,0 +1,3 @@
+(call target: (identifier) @keyword
+  (arguments . (alias) @protocol)
+  (#match? @keyword "^(defprotocol|defimpl)$")) @definition
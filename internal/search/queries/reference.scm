@@ -0,0 +1,8 @@
+(call target: [(identifier) (dot)]) @call
+
+(unary_operator
+  operator: "&"
+  operand: (binary_operator
+    left: [(call) (identifier)]
+    operator: "/"
+    right: (integer))) @capture
@@ -1 +1 @@
-((string) @str)
+([(string) (sigil) (charlist)] @str)
@@ -0,0 +1,2 @@
+(call target: (identifier) @keyword
+  (#match? @keyword "^(defstruct|defexception)$")) @struct
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/elixir"
+)
+
+// Capture is a node captured by a query given by the user
+type Capture struct {
+	Name     string // The capture name without the @
+	Line     uint32
+	Column   uint32
+	Contents string // The first line of the captured node
+}
+
+func (c Capture) Format() string {
+	return fmt.Sprintf("%d:%d: @%s %s", c.Line, c.Column, c.Name, c.Contents)
+}
+
+// compileUserQuery compiles a query given by the user, describing where the query is wrong
+// when it doesn't compile. User queries aren't kept in the query cache, which would grow
+// with every query sent to a long running server.
+func compileUserQuery(source string) (*sitter.Query, error) {
+	query, err := sitter.NewQuery([]byte(source), elixir.GetLanguage())
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	// FilterPredicates panics on a bad regex, so they're checked first
+	for i := range query.PatternCount() {
+		for _, steps := range query.PredicatesForPattern(i) {
+			operator := query.StringValueForId(steps[0].ValueId)
+			if operator != "match?" && operator != "not-match?" {
+				continue
+			}
+
+			if len(steps) < 3 || steps[2].Type != sitter.QueryPredicateStepTypeString {
+				return nil, fmt.Errorf("invalid query: #%s needs a capture and a regex", operator)
+			}
+
+			if _, err := regexp.Compile(query.StringValueForId(steps[2].ValueId)); err != nil {
+				return nil, fmt.Errorf("invalid query: %w", err)
+			}
+		}
+	}
+
+	return query, nil
+}
+
+// searchQuery runs the query in the search terms, returning every capture of the matches
+// that pass the query's #eq? and #match? predicates. A node captured more than once under
+// the same name is only returned once.
+func searchQuery(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	query := input.query
+	if query == nil {
+		var err error
+		query, err = compileUserQuery(input.SearchTerms)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	captures := []Capture{}
+	seen := map[string]bool{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		for _, capture := range match.Captures {
+			name := query.CaptureNameForId(capture.Index)
+			key := fmt.Sprintf("%s:%d:%d", name, capture.Node.StartByte(), capture.Node.EndByte())
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			captures = append(captures, Capture{
+				Name:     name,
+				Line:     capture.Node.StartPoint().Row,
+				Column:   capture.Node.StartPoint().Column,
+				Contents: strings.SplitN(capture.Node.Content(contents), "\n", 2)[0],
+			})
+		}
+	}
+
+	// matches of different patterns can overlap, so put the captures back in file order
+	sort.SliceStable(captures, func(i, j int) bool {
+		if captures[i].Line != captures[j].Line {
+			return captures[i].Line < captures[j].Line
+		}
+		return captures[i].Column < captures[j].Column
+	})
+
+	results := []ResultsFormatter{}
+	for _, capture := range captures {
+		results = append(results, capture)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchQuery(t *testing.T) {
+	root, contents := readTestFile(t)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{
+			`(call target: (dot left: (alias) @module right: (identifier) @fn) (#eq? @module "Repo"))`,
+			[]string{
+				"12:25: @module Repo", "12:30: @fn get!",
+				"15:4: @module Repo", "15:9: @fn get_by",
+				"22:7: @module Repo", "22:12: @fn update",
+			},
+		},
+		{
+			`(call target: (identifier) @def (arguments (identifier) @name) (#match? @def "^defp?$") (#match? @name "_str$"))`,
+			[]string{"47:2: @def def", "47:6: @name blue_str"},
+		},
+		{
+			`((string) @str (#not-match? @str "string"))`,
+			[]string{
+				`1:13: @str """`, `9:7: @str """`,
+				`32:23: @str "red"`, `33:17: @str "green"`,
+				`36:14: @str "Hello #{user.first}, your favorite color is: #{color}"`,
+				`47:20: @str "blue"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		results, err := searchQuery(root, contents, &SearchInput{SearchTerms: test.query})
+		if err != nil {
+			t.Errorf("search query failed: %v", err)
+		}
+
+		got := []string{}
+		for _, result := range results {
+			got = append(got, result.Format())
+		}
+
+		if strings.Join(got, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.query, strings.Join(got, "\n"), strings.Join(test.expected, "\n"))
+		}
+	}
+}
+
+func TestSearchQueryInvalid(t *testing.T) {
+	root, contents := readTestFile(t)
+	for _, query := range []string{"(call", `((identifier) @id (#match? @id "("))`} {
+		if _, err := searchQuery(root, contents, &SearchInput{SearchTerms: query}); err == nil || !strings.Contains(err.Error(), "invalid query") {
+			t.Errorf("%s: got %v want an invalid query error", query, err)
+		}
+	}
+}
@@ -0,0 +1,634 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Ref is a reference to a function from a call, a capture like &Mod.fun/1 or a defdelegate.
+type Ref struct {
+	Module   string   `json:"module"`   // The module of the referenced function, empty when it couldn't be resolved
+	Name     string   `json:"name"`     // The referenced function
+	Arity    int      `json:"arity"`    // The arity of the referenced function, including any piped argument, or -1 when unknown
+	Kind     string   `json:"kind"`     // call, import, capture, defdelegate or apply
+	Caller   string   `json:"caller"`   // Module.function/arity making the reference, or the module outside of functions
+	Line     uint32   `json:"line"`     // The file row the reference begins on
+	Column   uint32   `json:"column"`   // The file column the reference begins on
+	Contents string   `json:"contents"` // The first line of the reference
+	Imports  []string `json:"-"`        // Modules a local call could have been imported from without only:
+}
+
+func (r Ref) Format() string {
+	format := fmt.Sprintf("%d:[%s] %s", r.Line, r.Caller, r.Contents)
+	if r.Kind != "call" {
+		format += fmt.Sprintf(" (%s)", r.Kind)
+	}
+
+	return format
+}
+
+// callerModule returns the module of a Ref.Caller, which is either Module.function/arity
+// or just the module for references made outside of functions.
+func callerModule(caller string) string {
+	if idx := strings.LastIndex(caller, "/"); idx >= 0 {
+		return caller[:strings.LastIndex(caller[:idx], ".")]
+	}
+
+	return caller
+}
+
+// MFA returns the referenced function in Module.function/arity form
+func (r Ref) MFA() string {
+	return fmt.Sprintf("%s.%s/%d", r.Module, r.Name, r.Arity)
+}
+
+// Import is an import of a module into another module, optionally limited with only: or
+// except:.
+type Import struct {
+	Module   string   // The importing module
+	Imported string   // The imported module
+	Only     []string // name/arity of the functions given to only:
+	Except   []string // name/arity of the functions given to except:
+	Line     uint32
+}
+
+// Allows checks if the import brings the function into scope
+func (i Import) Allows(name string, arity int) bool {
+	signature := fmt.Sprintf("%s/%d", name, arity)
+	if len(i.Only) > 0 && !slices.Contains(i.Only, signature) {
+		return false
+	}
+
+	return !slices.Contains(i.Except, signature)
+}
+
+//go:embed queries/import.scm
+var importQuery string
+
+//go:embed queries/reference.scm
+var referenceQuery string
+
+// calls that define or structure code rather than reference a function
+var structuralCalls = []string{
+	"def", "defp", "defmacro", "defmacrop", "defguard", "defguardp", "defdelegate", "defmodule",
+	"defprotocol", "defimpl", "defstruct", "defexception", "alias", "import", "require", "use",
+}
+
+// attributes whose contents are types rather than code
+var typeAttributes = []string{"spec", "callback", "macrocallback", "type", "typep", "opaque"}
+
+// Generate a list of all imports
+func parseImports(root *sitter.Node, contents []byte, modules []Module, aliases []Alias) ([]Import, error) {
+	query, err := newQuery(importQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	imports := []Import{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		var node, imported *sitter.Node
+		for _, capture := range match.Captures {
+			switch query.CaptureNameForId(capture.Index) {
+			case "import":
+				node = capture.Node
+			case "module":
+				imported = capture.Node
+			}
+		}
+
+		if node == nil || imported == nil {
+			continue
+		}
+
+		imp := Import{
+			Imported: findFullModulePath(imported.Content(contents), aliases),
+			Line:     node.StartPoint().Row,
+		}
+		if module := enclosingModule(node, modules); module != nil {
+			imp.Module = module.Name
+		}
+
+		// only: and except: take a keyword list of name: arity
+		if args := imported.Parent(); args != nil {
+			imp.Only = keywordOption(args, contents, "only")
+			imp.Except = keywordOption(args, contents, "except")
+		}
+
+		imports = append(imports, imp)
+	}
+
+	return imports, nil
+}
+
+// get a keyword list option like only: [get: 1, put: 2] as name/arity strings
+func keywordOption(args *sitter.Node, contents []byte, option string) []string {
+	signatures := []string{}
+	for i := range int(args.NamedChildCount()) {
+		keywords := args.NamedChild(i)
+		if keywords.Type() != "keywords" {
+			continue
+		}
+
+		for j := range int(keywords.NamedChildCount()) {
+			pair := keywords.NamedChild(j)
+			if keywordKey(pair, contents) != option {
+				continue
+			}
+
+			value := pair.ChildByFieldName("value")
+			for k := range int(value.NamedChildCount()) {
+				list := value.NamedChild(k)
+				if list.Type() != "keywords" {
+					continue
+				}
+
+				for l := range int(list.NamedChildCount()) {
+					fn := list.NamedChild(l)
+					signatures = append(signatures, fmt.Sprintf("%s/%s", keywordKey(fn, contents), fn.ChildByFieldName("value").Content(contents)))
+				}
+			}
+		}
+	}
+
+	return signatures
+}
+
+// get the key of a keyword pair without the trailing colon
+func keywordKey(pair *sitter.Node, contents []byte) string {
+	key := pair.ChildByFieldName("key")
+	if pair.Type() != "pair" || key == nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(strings.TrimSpace(key.Content(contents)), ":")
+}
+
+// get the value of a keyword option like to: in a call's arguments
+func keywordValue(args *sitter.Node, contents []byte, option string) *sitter.Node {
+	for i := range int(args.NamedChildCount()) {
+		keywords := args.NamedChild(i)
+		if keywords.Type() != "keywords" {
+			continue
+		}
+
+		for j := range int(keywords.NamedChildCount()) {
+			if pair := keywords.NamedChild(j); keywordKey(pair, contents) == option {
+				return pair.ChildByFieldName("value")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Generate a list of all function references. Remote calls are resolved through aliases
+// and local calls to functions defined in the same module or imported with only:. Local
+// calls that could come from an import without only: are left for resolveImports.
+func parseRefs(root *sitter.Node, contents []byte, modules []Module, defs []FuncDef, aliases []Alias, imports []Import) ([]Ref, error) {
+	query, err := newQuery(referenceQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	refs := []Ref{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, capture := range match.Captures {
+			node := capture.Node
+			var ref *Ref
+
+			switch query.CaptureNameForId(capture.Index) {
+			case "capture":
+				ref = captureRef(node, contents, modules, aliases)
+			case "call":
+				if isCaptureHead(node) || isDefHead(node, contents) || inTypeAttribute(node, contents) {
+					continue
+				}
+				ref = callRef(node, contents, modules, aliases)
+			}
+
+			if ref == nil {
+				continue
+			}
+
+			ref.Line = node.StartPoint().Row
+			ref.Column = node.StartPoint().Column
+			ref.Contents = strings.TrimSpace(strings.SplitN(node.Content(contents), "\n", 2)[0])
+			ref.Caller = callerOf(node, modules, defs)
+
+			if ref.Module == "" {
+				resolveLocalRef(ref, node, modules, defs, imports)
+			}
+
+			refs = append(refs, *ref)
+		}
+	}
+
+	return refs, nil
+}
+
+// build a reference from a call like Mod.fun(a), fun(a) or a defdelegate
+func callRef(node *sitter.Node, contents []byte, modules []Module, aliases []Alias) *Ref {
+	target := node.ChildByFieldName("target")
+	arity := callArity(node)
+
+	// the left side of a pipe is the first argument
+	if parent := node.Parent(); parent != nil && parent.Type() == "binary_operator" &&
+		parent.ChildByFieldName("operator").Type() == "|>" && parent.ChildByFieldName("right").Equal(node) {
+		arity++
+	}
+
+	if target.Type() == "dot" {
+		module := dotModule(target.ChildByFieldName("left"), node, contents, modules, aliases)
+		right := target.ChildByFieldName("right")
+		if module == "" || right == nil || right.Type() != "identifier" {
+			return nil
+		}
+
+		if (module == "Kernel" || module == ":erlang") && right.Content(contents) == "apply" && arity == 3 {
+			if ref := applyRef(node, contents, modules, aliases); ref != nil {
+				return ref
+			}
+		}
+
+		return &Ref{Module: module, Name: right.Content(contents), Arity: arity, Kind: "call"}
+	}
+
+	name := target.Content(contents)
+	if name == "defdelegate" {
+		return delegateRef(node, contents, aliases)
+	}
+
+	if slices.Contains(structuralCalls, name) {
+		return nil
+	}
+
+	if name == "apply" && arity == 3 {
+		if ref := applyRef(node, contents, modules, aliases); ref != nil {
+			return ref
+		}
+	}
+
+	return &Ref{Name: name, Arity: arity, Kind: "call"}
+}
+
+// build a reference from a capture like &Mod.fun/2 or &fun/2
+func captureRef(node *sitter.Node, contents []byte, modules []Module, aliases []Alias) *Ref {
+	operand := node.ChildByFieldName("operand")
+	arity, err := strconv.Atoi(operand.ChildByFieldName("right").Content(contents))
+	if err != nil {
+		return nil
+	}
+
+	head := operand.ChildByFieldName("left")
+	if head.Type() == "identifier" {
+		return &Ref{Name: head.Content(contents), Arity: arity, Kind: "capture"}
+	}
+
+	target := head.ChildByFieldName("target")
+	if target == nil || target.Type() != "dot" {
+		return nil
+	}
+
+	module := dotModule(target.ChildByFieldName("left"), node, contents, modules, aliases)
+	if module == "" {
+		return nil
+	}
+
+	return &Ref{Module: module, Name: target.ChildByFieldName("right").Content(contents), Arity: arity, Kind: "capture"}
+}
+
+// build a reference to the function a defdelegate delegates to
+func delegateRef(node *sitter.Node, contents []byte, aliases []Alias) *Ref {
+	args := node.NamedChild(1)
+	if args == nil || args.Type() != "arguments" || args.NamedChildCount() == 0 {
+		return nil
+	}
+
+	to := keywordValue(args, contents, "to")
+	if to == nil || to.Type() != "alias" {
+		return nil
+	}
+
+	name, arity := funcHead(args.NamedChild(0), contents)
+	if as := keywordValue(args, contents, "as"); as != nil {
+		name = strings.TrimPrefix(as.Content(contents), ":")
+	}
+
+	return &Ref{Module: findFullModulePath(to.Content(contents), aliases), Name: name, Arity: arity, Kind: "defdelegate"}
+}
+
+// build a reference from apply(Mod, :fun, args) when the module and function are literals.
+// The arity is only known when the arguments are a literal list, otherwise it's -1.
+func applyRef(node *sitter.Node, contents []byte, modules []Module, aliases []Alias) *Ref {
+	var args *sitter.Node
+	for _, child := range namedChildren(node) {
+		if child.Type() == "arguments" {
+			args = child
+		}
+	}
+
+	if args == nil || args.NamedChildCount() != 3 {
+		return nil
+	}
+
+	module := dotModule(args.NamedChild(0), node, contents, modules, aliases)
+	fun := args.NamedChild(1)
+	if module == "" || fun.Type() != "atom" {
+		return nil
+	}
+
+	arity := -1
+	if list := args.NamedChild(2); list.Type() == "list" {
+		arity = int(list.NamedChildCount())
+	}
+
+	return &Ref{Module: module, Name: strings.TrimPrefix(fun.Content(contents), ":"), Arity: arity, Kind: "apply"}
+}
+
+// get the module on the left side of a remote call. Aliases are resolved, __MODULE__ is the
+// enclosing module and erlang modules keep their atom.
+func dotModule(left *sitter.Node, node *sitter.Node, contents []byte, modules []Module, aliases []Alias) string {
+	if left == nil {
+		return ""
+	}
+
+	switch left.Type() {
+	case "alias":
+		return findFullModulePath(left.Content(contents), aliases)
+	case "atom":
+		return left.Content(contents)
+	case "identifier":
+		if module := enclosingModule(node, modules); module != nil && left.Content(contents) == "__MODULE__" {
+			return module.Name
+		}
+	}
+
+	return ""
+}
+
+// resolve a local call to a function in the same module, or a function imported with only:.
+// Otherwise keep a list of modules it might have been imported from.
+func resolveLocalRef(ref *Ref, node *sitter.Node, modules []Module, defs []FuncDef, imports []Import) {
+	module := enclosingModule(node, modules)
+	if module == nil {
+		return
+	}
+
+	for _, def := range defs {
+		if def.Module == module.Name && def.Name == ref.Name && def.HasArity(ref.Arity) {
+			ref.Module = module.Name
+			return
+		}
+	}
+
+	for _, imp := range imports {
+		if imp.Module != module.Name || !imp.Allows(ref.Name, ref.Arity) {
+			continue
+		}
+
+		if len(imp.Only) > 0 {
+			ref.Module = imp.Imported
+			ref.Kind = "import"
+			ref.Imports = nil
+			return
+		}
+
+		ref.Imports = append(ref.Imports, imp.Imported)
+	}
+}
+
+// the function making a reference, or the module for references outside of functions
+func callerOf(node *sitter.Node, modules []Module, defs []FuncDef) string {
+	var caller *FuncDef
+	for i, def := range defs {
+		if def.Kind != "defdelegate" && containsNode(def.node, node) {
+			caller = &defs[i]
+		}
+	}
+
+	if caller != nil {
+		return fmt.Sprintf("%s.%s", caller.Module, caller.Signature())
+	}
+
+	if module := enclosingModule(node, modules); module != nil {
+		return module.Name
+	}
+
+	return ""
+}
+
+// check if a call is the Mod.fun in &Mod.fun/2
+func isCaptureHead(node *sitter.Node) bool {
+	parent := node.Parent()
+	if parent == nil || parent.Type() != "binary_operator" || parent.ChildByFieldName("operator").Type() != "/" {
+		return false
+	}
+
+	grandparent := parent.Parent()
+	return grandparent != nil && grandparent.Type() == "unary_operator" && grandparent.ChildByFieldName("operator").Type() == "&"
+}
+
+// check if a call is the head of a definition like the name(a) in def name(a)
+func isDefHead(node *sitter.Node, contents []byte) bool {
+	parent := node.Parent()
+	if parent != nil && parent.Type() == "binary_operator" && parent.ChildByFieldName("operator").Type() == "when" {
+		parent = parent.Parent()
+	}
+
+	if parent == nil || parent.Type() != "arguments" {
+		return false
+	}
+
+	def := parent.Parent()
+	target := def.ChildByFieldName("target")
+	return def.Type() == "call" && target != nil && slices.Contains(structuralCalls, target.Content(contents))
+}
+
+// check if a node is inside of a type attribute like @spec
+func inTypeAttribute(node *sitter.Node, contents []byte) bool {
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		if name, _ := attribute(parent, contents); slices.Contains(typeAttributes, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// functionAt finds the function referenced at a position in a file, or the function whose
+// head the position is in. Functions being defined are returned as a reference to their
+// first arity, with Kind set to the def keyword.
+func functionAt(file *FileIndex, root *sitter.Node, contents []byte, point sitter.Point) (Ref, bool) {
+	node := root.NamedDescendantForPointRange(point, point)
+	if node == nil {
+		return Ref{}, false
+	}
+
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return Ref{}, false
+	}
+
+	defs, err := parseFuncDefs(root, contents, modules)
+	if err != nil {
+		return Ref{}, false
+	}
+
+	for _, def := range defs {
+		if head := defHead(def); head != nil && containsNode(head, node) {
+			return Ref{
+				Module: def.Module,
+				Name:   def.Name,
+				Arity:  def.Arity,
+				Kind:   def.Kind,
+				Line:   def.Line,
+				Column: def.Column,
+			}, true
+		}
+	}
+
+	return refAtNode(file, node)
+}
+
+// refAtNode finds the reference starting at the node or one of its parents.
+func refAtNode(file *FileIndex, node *sitter.Node) (Ref, bool) {
+	for parent := node; parent != nil; parent = parent.Parent() {
+		for _, ref := range file.Refs {
+			if ref.Line == parent.StartPoint().Row && ref.Column == parent.StartPoint().Column && ref.Module != "" {
+				return ref, true
+			}
+		}
+	}
+
+	return Ref{}, false
+}
+
+// defHead returns the head of a definition, like name(a) in def name(a), do: a
+func defHead(def FuncDef) *sitter.Node {
+	for i := range int(def.node.NamedChildCount()) {
+		if args := def.node.NamedChild(i); args.Type() == "arguments" && args.NamedChildCount() > 0 {
+			return args.NamedChild(0)
+		}
+	}
+
+	return nil
+}
+
+// parseMFA splits Module.function/arity into its parts. The arity is -1 when it's
+// missing, which matches any arity.
+func parseMFA(mfa string) (string, string, int, error) {
+	arity := -1
+	if idx := strings.LastIndex(mfa, "/"); idx >= 0 {
+		var err error
+		arity, err = strconv.Atoi(mfa[idx+1:])
+		if err != nil {
+			return "", "", 0, fmt.Errorf("invalid arity in %s", mfa)
+		}
+		mfa = mfa[:idx]
+	}
+
+	idx := strings.LastIndex(mfa, ".")
+	if idx <= 0 || idx == len(mfa)-1 {
+		return "", "", 0, fmt.Errorf("expected Module.function/arity, got %s", mfa)
+	}
+
+	return mfa[:idx], mfa[idx+1:], arity, nil
+}
+
+// findRefs finds every reference to a function across the project. A function with default
+// arguments is referenced by calls with any arity it accepts. References are deduplicated
+// by their position.
+func findRefs(files []*FileIndex, mfa string) (map[string][]Ref, error) {
+	module, name, arity, err := parseMFA(mfa)
+	if err != nil {
+		return nil, err
+	}
+
+	arities := []int{arity}
+	for _, file := range files {
+		for _, def := range file.Defs {
+			if def.Module == module && def.Name == name && arity >= 0 && def.HasArity(arity) {
+				for a := def.Arity - def.Defaults; a <= def.Arity; a++ {
+					if !slices.Contains(arities, a) {
+						arities = append(arities, a)
+					}
+				}
+			}
+		}
+	}
+
+	found := map[string][]Ref{}
+	for _, file := range files {
+		seen := map[string]bool{}
+		for _, ref := range file.Refs {
+			key := fmt.Sprintf("%d:%d", ref.Line, ref.Column)
+			if ref.Module != module || ref.Name != name || (arity >= 0 && ref.Arity >= 0 && !slices.Contains(arities, ref.Arity)) || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			found[file.Path] = append(found[file.Path], ref)
+		}
+	}
+
+	return found, nil
+}
+
+// Refs prints every reference to a function given as Module.function/arity, grouped by
+// file.
+func Refs(dir string, mfa string) error {
+	files, err := indexProject(dir)
+	if err != nil {
+		return err
+	}
+
+	found, err := findRefs(files, mfa)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		refs := found[file.Path]
+		if len(refs) == 0 {
+			continue
+		}
+
+		relFile, err := filepath.Rel(dir, file.Path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(relFile)
+		for _, ref := range refs {
+			fmt.Println(ref.Format())
+		}
+		fmt.Println("")
+	}
+
+	return nil
+}
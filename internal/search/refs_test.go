@@ -0,0 +1,114 @@
+package search
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseImports(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/accounts.ex")
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		t.Errorf("parse modules failed: %v", err)
+	}
+
+	imports, err := parseImports(root, contents, modules, []Alias{})
+	if err != nil {
+		t.Errorf("parse imports failed: %v", err)
+	}
+
+	expected := []Import{
+		{Module: "TestApp.Accounts", Imported: "TestApp.Accounts.Users", Only: []string{"get_user!/1"}, Except: []string{}, Line: 1},
+		{Module: "TestApp.Accounts.Admin", Imported: "TestApp.Accounts.Users", Only: []string{}, Except: []string{}, Line: 14},
+	}
+
+	if !reflect.DeepEqual(imports, expected) {
+		t.Errorf("got %+v want %+v", imports, expected)
+	}
+}
+
+func TestParseMFA(t *testing.T) {
+	module, name, arity, err := parseMFA("TestApp.Accounts.Users.get_user!/1")
+	if err != nil || module != "TestApp.Accounts.Users" || name != "get_user!" || arity != 1 {
+		t.Errorf("got %v %v %v %v", module, name, arity, err)
+	}
+
+	if _, _, arity, _ := parseMFA("Users.get_user!"); arity != -1 {
+		t.Errorf("got arity %v want -1", arity)
+	}
+
+	if _, _, _, err := parseMFA("get_user!/1"); err == nil {
+		t.Errorf("expected an error without a module")
+	}
+}
+
+func TestFindRefs(t *testing.T) {
+	files, err := indexProject("testdata")
+	if err != nil {
+		t.Errorf("index project failed: %v", err)
+	}
+
+	found, err := findRefs(files, "TestApp.Accounts.Users.get_user!/1")
+	if err != nil {
+		t.Errorf("find refs failed: %v", err)
+	}
+
+	output := map[string][]string{}
+	for file, refs := range found {
+		for _, ref := range refs {
+			output[file] = append(output[file], ref.Format())
+		}
+	}
+
+	expected := map[string][]string{
+		"testdata/accounts.ex": {
+			"3:[TestApp.Accounts] defdelegate fetch_user!(id), to: TestApp.Accounts.Users, as: :get_user! (defdelegate)",
+			"5:[TestApp.Accounts.load/1] &TestApp.Accounts.Users.get_user!/1 (capture)",
+			"9:[TestApp.Accounts.load_one/1] get_user!() (import)",
+			"16:[TestApp.Accounts.Admin.fetch/1] get_user!(id) (import)",
+		},
+		"testdata/doctests.ex": {
+			"24:[TestApp.Accounts.Lookup.find/2] Users.get_user!(id)",
+		},
+		"testdata/users_test.exs": {
+			"14:[TestApp.Accounts.UsersTest] Users.get_user!(user.id)",
+			"19:[TestApp.Accounts.UsersTest] Users.get_user!(-1)",
+		},
+	}
+
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("got %v want %v", output, expected)
+	}
+}
+
+func TestFindRefsDefaultArguments(t *testing.T) {
+	dir := writeProjectFiles(t, map[string]string{
+		"users.ex": `defmodule App.Users do
+  def list(opts \\ []), do: opts
+end
+`,
+		"web.ex": `defmodule App.Web do
+  def index, do: {App.Users.list(), App.Users.list(limit: 1), App.Users.list(1, 2)}
+end
+`,
+	})
+
+	// calls with either arity list/1 accepts call it
+	for _, mfa := range []string{"App.Users.list/0", "App.Users.list/1"} {
+		found, err := findRefs(mustIndex(t, dir), mfa)
+		if err != nil {
+			t.Fatalf("find refs failed: %v", err)
+		}
+
+		got := []string{}
+		for _, ref := range found[filepath.Join(dir, "web.ex")] {
+			got = append(got, ref.Contents)
+		}
+
+		expected := []string{"App.Users.list()", "App.Users.list(limit: 1)"}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: got %v want %v", mfa, got, expected)
+		}
+	}
+}
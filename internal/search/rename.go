@@ -0,0 +1,771 @@
+package search
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// RenameInput holds the input for renaming a module or function across a project.
+type RenameInput struct {
+	Dir  string
+	From string
+	To   string
+
+	// Move moves the file defining a renamed module to the path matching its new name.
+	Move bool
+
+	// DryRun prints the changes as a unified diff instead of writing them.
+	DryRun bool
+
+	// Confirm is asked before writing a renamed function, after its diff has been printed.
+	Confirm func() bool
+}
+
+var moduleNameRegex = regexp.MustCompile(`^[A-Z][A-Za-z0-9_]*(\.[A-Z][A-Za-z0-9_]*)*$`)
+
+//go:embed queries/alias_ref.scm
+var aliasRefQuery string
+
+//go:embed queries/module_atom.scm
+var moduleAtomQuery string
+
+// RenameModule renames a module and the modules nested under it, updating its defmodule
+// and every alias, remote call, struct, @behaviour, use, import, require and :"Elixir."
+// atom naming it.
+func RenameModule(input *RenameInput) error {
+	changes, err := renameModuleFiles(input)
+	if err != nil {
+		return err
+	}
+
+	return applyChanges(input.Dir, changes, input.DryRun)
+}
+
+// moduleRename renames the module From to To, along with any module under it.
+type moduleRename struct {
+	from string
+	to   string
+}
+
+// rename returns the new name of a module, and false if the module isn't renamed.
+func (r moduleRename) rename(module string) (string, bool) {
+	if module == r.from {
+		return r.to, true
+	}
+
+	if rest, ok := strings.CutPrefix(module, r.from+"."); ok {
+		return r.to + "." + rest, true
+	}
+
+	return module, false
+}
+
+// renameModuleFiles finds the changes renaming a module makes without writing them.
+func renameModuleFiles(input *RenameInput) ([]fileChange, error) {
+	for _, name := range []string{input.From, input.To} {
+		if !moduleNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid module name %q", name)
+		}
+	}
+
+	rename := moduleRename{from: input.From, to: input.To}
+
+	changes := []fileChange{}
+	defined := false
+	err := walkElixirFiles(input.Dir, func(path string) error {
+		root, contents, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+
+		edits, defines, topLevel, err := renameModuleEdits(root, contents, rename)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		defined = defined || defines
+
+		relPath, err := filepath.Rel(input.Dir, path)
+		if err != nil {
+			return err
+		}
+
+		change := fileChange{Path: relPath, Before: contents, After: applyEdits(contents, edits)}
+		if input.Move && topLevel {
+			change.NewPath = movedModulePath(relPath, rename)
+		}
+
+		if change.NewPath != "" || len(edits) > 0 {
+			changes = append(changes, change)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !defined {
+		return nil, fmt.Errorf("module %s isn't defined in the project", input.From)
+	}
+
+	for _, change := range changes {
+		if change.NewPath == "" {
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(input.Dir, change.NewPath)); err == nil {
+			return nil, fmt.Errorf("can't move %s to %s, it already exists", change.Path, change.NewPath)
+		}
+	}
+
+	return changes, nil
+}
+
+// renameModuleEdits returns the edits renaming a module in a file, whether the file defines
+// the module and whether it is defined at the top level of the file.
+func renameModuleEdits(root *sitter.Node, contents []byte, rename moduleRename) ([]textEdit, bool, bool, error) {
+	modules, err := parseModules(root, contents)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	aliases, err := parseAliases(root, contents)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	query, err := newQuery(aliasRefQuery)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	edits := []textEdit{}
+	defines, topLevel := false, false
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, capture := range match.Captures {
+			node := capture.Node
+			name := node.Content(contents)
+
+			var edit *textEdit
+			switch aliasContext(node, contents) {
+			case "defmodule":
+				module := moduleDefinedBy(node, modules)
+				newName, ok := rename.rename(module.Name)
+				if !ok {
+					continue
+				}
+
+				defines = defines || module.Name == rename.from
+
+				// nested modules are named relative to their parent
+				if parent, ok := strings.CutSuffix(module.Name, "."+name); ok {
+					newParent, _ := rename.rename(parent)
+					relative, ok := strings.CutPrefix(newName, newParent+".")
+					if !ok {
+						return nil, false, false, fmt.Errorf("%s can't be renamed to %s while it is nested in %s", module.Name, newName, newParent)
+					}
+					newName = relative
+				} else {
+					topLevel = topLevel || module.Name == rename.from
+				}
+
+				edit = &textEdit{Start: node.StartByte(), End: node.EndByte(), Text: newName}
+			case "alias":
+				if newName, ok := rename.rename(name); ok {
+					edit = &textEdit{Start: node.StartByte(), End: node.EndByte(), Text: newName}
+				}
+			case "alias_prefix":
+				edit = renameGroupedAliases(node.Parent().Parent().Parent(), contents, rename)
+			case "alias_group":
+				// handled with the prefix
+			case "alias_as":
+				// the name given with as: is kept
+			default:
+				if newName, ok := renameAliasRef(name, aliases, rename); ok && newName != name {
+					edit = &textEdit{Start: node.StartByte(), End: node.EndByte(), Text: newName}
+				}
+			}
+
+			if edit != nil {
+				edits = append(edits, *edit)
+			}
+		}
+	}
+
+	atomEdits, err := renameModuleAtoms(root, contents, rename)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	return append(edits, atomEdits...), defines, topLevel, nil
+}
+
+// renameModuleAtoms renames modules written as erlang style atoms like :"Elixir.App.Users".
+// Atoms built with interpolation are left alone.
+func renameModuleAtoms(root *sitter.Node, contents []byte, rename moduleRename) ([]textEdit, error) {
+	query, err := newQuery(moduleAtomQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	edits := []textEdit{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		for _, capture := range match.Captures {
+			node := capture.Node
+			if node.Parent().NamedChildCount() != 1 {
+				continue
+			}
+
+			module, ok := strings.CutPrefix(node.Content(contents), "Elixir.")
+			if !ok {
+				continue
+			}
+
+			if newName, ok := rename.rename(module); ok {
+				edits = append(edits, textEdit{Start: node.StartByte(), End: node.EndByte(), Text: "Elixir." + newName})
+			}
+		}
+	}
+
+	return edits, nil
+}
+
+// aliasContext describes where an alias is used. It is defmodule for the name of a module
+// definition, alias for the module in alias Module, alias_prefix and alias_group for the
+// parts of alias Prefix.{A, B}, alias_as for the name given with as: and an empty string
+// for any other use.
+func aliasContext(node *sitter.Node, contents []byte) string {
+	parent := node.Parent()
+	if parent == nil {
+		return ""
+	}
+
+	switch {
+	case parent.Type() == "arguments" && parent.NamedChild(0).Equal(node):
+		switch callTarget(parent.Parent(), contents) {
+		case "defmodule":
+			return "defmodule"
+		case "alias":
+			return "alias"
+		}
+	case parent.Type() == "dot" && parent.ChildByFieldName("left").Equal(node) && parent.ChildByFieldName("right").Type() == "tuple":
+		if callTarget(parent.Parent().Parent(), contents) == "alias" {
+			return "alias_prefix"
+		}
+	case parent.Type() == "tuple" && parent.Parent().Type() == "dot":
+		if callTarget(parent.Parent().Parent().Parent(), contents) == "alias" {
+			return "alias_group"
+		}
+	case parent.Type() == "pair" && insideCall(node, contents, "alias"):
+		return "alias_as"
+	}
+
+	return ""
+}
+
+// callTarget returns the name of the function a call node calls, like def in def name(a)
+func callTarget(node *sitter.Node, contents []byte) string {
+	if node == nil || node.Type() != "call" {
+		return ""
+	}
+
+	if target := node.ChildByFieldName("target"); target != nil && target.Type() == "identifier" {
+		return target.Content(contents)
+	}
+
+	return ""
+}
+
+// moduleDefinedBy returns the module whose defmodule name is the node
+func moduleDefinedBy(node *sitter.Node, modules []Module) Module {
+	def := node.Parent().Parent()
+	for _, module := range modules {
+		if module.node.Equal(def) {
+			return module
+		}
+	}
+
+	return Module{}
+}
+
+// renameGroupedAliases rewrites alias Prefix.{A, B} when any of the aliases are renamed.
+// Renamed aliases that still share the prefix stay in the group, the others are split out
+// into their own alias after it.
+func renameGroupedAliases(call *sitter.Node, contents []byte, rename moduleRename) *textEdit {
+	dot := call.ChildByFieldName("target").NextNamedSibling().NamedChild(0)
+	prefix := dot.ChildByFieldName("left").Content(contents)
+
+	// the whole group moves when the prefix is renamed
+	if newPrefix, ok := rename.rename(prefix); ok {
+		left := dot.ChildByFieldName("left")
+		return &textEdit{Start: left.StartByte(), End: left.EndByte(), Text: newPrefix}
+	}
+
+	grouped := []string{}
+	split := []string{}
+	renamed := false
+	for _, child := range namedChildren(dot.ChildByFieldName("right")) {
+		name := child.Content(contents)
+		newName, ok := rename.rename(prefix + "." + name)
+		if !ok {
+			grouped = append(grouped, name)
+			continue
+		}
+
+		renamed = true
+		if rest, ok := strings.CutPrefix(newName, prefix+"."); ok {
+			grouped = append(grouped, rest)
+		} else {
+			split = append(split, newName)
+		}
+	}
+
+	if !renamed {
+		return nil
+	}
+
+	lineStart := strings.LastIndexByte(string(contents[:call.StartByte()]), '\n') + 1
+	line := string(contents[lineStart:call.StartByte()])
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+	lines := []string{}
+	switch len(grouped) {
+	case 0:
+	case 1:
+		lines = append(lines, fmt.Sprintf("alias %s.%s", prefix, grouped[0]))
+	default:
+		lines = append(lines, fmt.Sprintf("alias %s.{%s}", prefix, strings.Join(grouped, ", ")))
+	}
+
+	for _, name := range split {
+		lines = append(lines, "alias "+name)
+	}
+
+	return &textEdit{Start: call.StartByte(), End: call.EndByte(), Text: strings.Join(lines, "\n"+indent)}
+}
+
+// renameAliasRef renames a module referenced by name, which may start with an alias of
+// the file. The new name keeps using the alias when it still can.
+func renameAliasRef(name string, aliases []Alias, rename moduleRename) (string, bool) {
+	first, rest, _ := strings.Cut(name, ".")
+
+	var alias *Alias
+	for i := range aliases {
+		if aliases[i].As == first {
+			alias = &aliases[i]
+		}
+	}
+
+	if alias == nil {
+		return rename.rename(name)
+	}
+
+	module := alias.ModulePath
+	if rest != "" {
+		module += "." + rest
+	}
+
+	newModule, ok := rename.rename(module)
+	if !ok {
+		return name, false
+	}
+
+	// the alias itself is renamed, and when it wasn't given a name with as: the name it
+	// makes available changes with it
+	aliasModule, _ := rename.rename(alias.ModulePath)
+	aliasAs := alias.As
+	if alias.As == alias.ModulePath[strings.LastIndex(alias.ModulePath, ".")+1:] {
+		aliasAs = aliasModule[strings.LastIndex(aliasModule, ".")+1:]
+	}
+
+	if newModule == aliasModule {
+		return aliasAs, true
+	}
+
+	if rest, ok := strings.CutPrefix(newModule, aliasModule+"."); ok {
+		return aliasAs + "." + rest, true
+	}
+
+	return newModule, true
+}
+
+// movedModulePath is the path of a file after renaming the module it defines. Files at
+// the conventional path for their module, like lib/my_app/users.ex for MyApp.Users, keep
+// the same root and others are moved to lib/.
+func movedModulePath(path string, rename moduleRename) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.ToSlash(path), ext)
+	newFile := modulePath(rename.to)
+
+	if root, ok := strings.CutSuffix(base, modulePath(rename.from)); ok {
+		return filepath.FromSlash(root + newFile + ext)
+	}
+
+	return filepath.FromSlash("lib/" + newFile + ext)
+}
+
+// modulePath converts a module name to the path of its file without an extension, the same
+// way as Macro.underscore, eg. MyApp.HTTPClient becomes my_app/http_client.
+func modulePath(module string) string {
+	segments := []string{}
+	for _, segment := range strings.Split(module, ".") {
+		runes := []rune(segment)
+		out := []rune{}
+		for i, r := range runes {
+			if i > 0 && unicode.IsUpper(r) {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					out = append(out, '_')
+				}
+			}
+			out = append(out, unicode.ToLower(r))
+		}
+		segments = append(segments, string(out))
+	}
+
+	return strings.Join(segments, "/")
+}
+
+var functionNameRegex = regexp.MustCompile(`^[a-z_][A-Za-z0-9_]*[?!]?$`)
+
+// RenameFunction renames the function From, given as Module.function/arity, to the name
+// To. Its definition clauses, local, remote and imported calls, captures, defdelegates,
+// import options and @specs are updated while other arities are left alone. The changes
+// are always printed as a unified diff, and are only written when it isn't a dry run and
+// Confirm, if set, accepts them.
+func RenameFunction(input *RenameInput) error {
+	changes, err := renameFunctionFiles(input)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		fmt.Print(change.diff())
+	}
+
+	if input.DryRun || len(changes) == 0 || (input.Confirm != nil && !input.Confirm()) {
+		return nil
+	}
+
+	return writeChanges(input.Dir, changes)
+}
+
+// fnRename renames the function name in module, for the arities in arities.
+type fnRename struct {
+	module  string
+	name    string
+	newName string
+	arities []int
+}
+
+func (r fnRename) matches(module string, name string, arity int) bool {
+	return module == r.module && name == r.name && slices.Contains(r.arities, arity)
+}
+
+// renameFunctionFiles finds the changes renaming a function makes without writing them.
+func renameFunctionFiles(input *RenameInput) ([]fileChange, error) {
+	module, name, arity, err := parseMFA(input.From)
+	if err != nil {
+		return nil, err
+	}
+
+	if arity < 0 {
+		return nil, fmt.Errorf("give the arity of the function to rename, eg. %s/1", input.From)
+	}
+
+	if !functionNameRegex.MatchString(input.To) {
+		return nil, fmt.Errorf("invalid function name %q", input.To)
+	}
+
+	// everything is parsed again rather than read from the index to have the trees to edit
+	files := []*FileIndex{}
+	contents := map[string][]byte{}
+	roots := map[string]*sitter.Node{}
+	err = walkElixirFiles(input.Dir, func(path string) error {
+		root, fileContents, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+
+		file, err := indexTree(path, root, fileContents)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, file)
+		contents[path] = fileContents
+		roots[path] = root
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolveImports(files)
+
+	// a definition with default arguments defines several arities, which are renamed together
+	rename := fnRename{module: module, name: name, newName: input.To}
+	for _, file := range files {
+		for _, def := range file.Defs {
+			if def.Module == module && def.Name == name && def.HasArity(arity) {
+				for a := def.Arity - def.Defaults; a <= def.Arity; a++ {
+					if !slices.Contains(rename.arities, a) {
+						rename.arities = append(rename.arities, a)
+					}
+				}
+			}
+		}
+	}
+
+	if len(rename.arities) == 0 {
+		return nil, fmt.Errorf("%s isn't defined in the project", input.From)
+	}
+
+	for _, file := range files {
+		for _, def := range file.Defs {
+			for _, a := range rename.arities {
+				if def.Module == module && def.Name == input.To && def.HasArity(a) {
+					return nil, fmt.Errorf("%s.%s/%d is already defined", module, input.To, a)
+				}
+			}
+		}
+	}
+
+	changes := []fileChange{}
+	for _, file := range files {
+		fileContents := contents[file.Path]
+		edits := renameFunctionEdits(file, roots[file.Path], fileContents, rename)
+		if len(edits) == 0 {
+			continue
+		}
+
+		relPath, err := filepath.Rel(input.Dir, file.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, fileChange{Path: relPath, Before: fileContents, After: applyEdits(fileContents, edits)})
+	}
+
+	return changes, nil
+}
+
+// renameFunctionEdits returns the edits renaming a function in a file
+func renameFunctionEdits(file *FileIndex, root *sitter.Node, contents []byte, rename fnRename) []textEdit {
+	edits := []textEdit{}
+	renameNode := func(node *sitter.Node) {
+		if node != nil && node.Content(contents) == rename.name {
+			edits = append(edits, textEdit{Start: node.StartByte(), End: node.EndByte(), Text: rename.newName})
+		}
+	}
+
+	for _, def := range file.Defs {
+		if def.Module == rename.module && def.Name == rename.name && slices.ContainsFunc(rename.arities, def.HasArity) {
+			renameNode(headName(defHead(def)))
+
+			// a delegate without as: was delegating to the function with the old name
+			if def.Kind == "defdelegate" {
+				args := def.node.NamedChild(1)
+				if to := keywordValue(args, contents, "to"); to != nil && keywordValue(args, contents, "as") == nil {
+					edits = append(edits, textEdit{Start: to.EndByte(), End: to.EndByte(), Text: ", as: :" + rename.name})
+				}
+			}
+		}
+	}
+
+	for _, ref := range file.Refs {
+		if !rename.matches(ref.Module, ref.Name, ref.Arity) {
+			continue
+		}
+
+		node := refNode(root, contents, ref)
+		if node == nil {
+			continue
+		}
+
+		if ref.Kind == "defdelegate" {
+			edits = append(edits, renameDelegate(node, contents, rename))
+		} else {
+			renameNode(refName(node))
+		}
+	}
+
+	edits = append(edits, renameImportOptions(root, contents, file, rename)...)
+
+	// specs aren't references, but belong to the definition
+	for _, module := range file.Modules {
+		if module.Name != rename.module {
+			continue
+		}
+
+		for _, child := range moduleBody(module) {
+			if attr, args := attribute(child, contents); attr == "spec" && args != nil {
+				head := args.NamedChild(0)
+				for head != nil && head.Type() == "binary_operator" {
+					head = head.ChildByFieldName("left")
+				}
+
+				if head != nil && head.Type() == "call" && slices.Contains(rename.arities, callArity(head)) {
+					renameNode(head.ChildByFieldName("target"))
+				}
+			}
+		}
+	}
+
+	return edits
+}
+
+// headName returns the node naming the function in a definition head like name(a) when a > 1
+func headName(head *sitter.Node) *sitter.Node {
+	if head == nil {
+		return nil
+	}
+
+	if head.Type() == "binary_operator" {
+		head = head.ChildByFieldName("left")
+	}
+
+	if head.Type() == "identifier" {
+		return head
+	}
+
+	return head.ChildByFieldName("target")
+}
+
+// refNode finds the call or capture node a reference was made from by its position
+func refNode(root *sitter.Node, contents []byte, ref Ref) *sitter.Node {
+	point := sitter.Point{Row: ref.Line, Column: ref.Column}
+
+	// calls like Mod.a().b() start at the same position as the calls inside of them
+	for node := root.NamedDescendantForPointRange(point, point); node != nil; node = node.Parent() {
+		if node.StartPoint() != point {
+			continue
+		}
+
+		switch {
+		case ref.Kind == "defdelegate" && callTarget(node, contents) == "defdelegate":
+			return node
+		case ref.Kind == "capture" && node.Type() == "unary_operator":
+			return node
+		case (ref.Kind == "call" || ref.Kind == "import") && node.Type() == "call":
+			if name := refName(node); name != nil && name.Content(contents) == ref.Name {
+				return node
+			}
+		}
+	}
+
+	return nil
+}
+
+// refName returns the node naming the function in a call like Mod.fun(a) or fun(a), or in
+// a capture like &Mod.fun/1.
+func refName(node *sitter.Node) *sitter.Node {
+	if node.Type() == "unary_operator" {
+		head := node.ChildByFieldName("operand").ChildByFieldName("left")
+		if head.Type() == "identifier" {
+			return head
+		}
+		node = head
+	}
+
+	target := node.ChildByFieldName("target")
+	if target != nil && target.Type() == "dot" {
+		return target.ChildByFieldName("right")
+	}
+
+	return target
+}
+
+// renameDelegate changes the function a defdelegate delegates to. The as: option is added
+// when the delegate was relying on having the same name.
+func renameDelegate(node *sitter.Node, contents []byte, rename fnRename) textEdit {
+	args := node.NamedChild(1)
+	if as := keywordValue(args, contents, "as"); as != nil {
+		return textEdit{Start: as.StartByte(), End: as.EndByte(), Text: ":" + rename.newName}
+	}
+
+	to := keywordValue(args, contents, "to")
+	return textEdit{Start: to.EndByte(), End: to.EndByte(), Text: ", as: :" + rename.newName}
+}
+
+// renameImportOptions renames the function in the only: and except: options of imports of
+// its module.
+func renameImportOptions(root *sitter.Node, contents []byte, file *FileIndex, rename fnRename) []textEdit {
+	query, err := newQuery(importQuery)
+	if err != nil {
+		return nil
+	}
+
+	cursor := sitter.NewQueryCursor()
+	cursor.Exec(query, root)
+
+	edits := []textEdit{}
+	for {
+		// get the match and break out if we're done matching
+		match, ok := cursor.NextMatch()
+		if !ok {
+			break
+		}
+
+		match = cursor.FilterPredicates(match, contents)
+		for _, capture := range match.Captures {
+			if query.CaptureNameForId(capture.Index) != "module" ||
+				findFullModulePath(capture.Node.Content(contents), file.Aliases) != rename.module {
+				continue
+			}
+
+			for _, option := range []string{"only", "except"} {
+				list := keywordValue(capture.Node.Parent(), contents, option)
+				if list == nil {
+					continue
+				}
+
+				for _, keywords := range namedChildren(list) {
+					for _, pair := range namedChildren(keywords) {
+						arity, err := strconv.Atoi(pair.ChildByFieldName("value").Content(contents))
+						if err != nil || keywordKey(pair, contents) != rename.name || !slices.Contains(rename.arities, arity) {
+							continue
+						}
+
+						key := pair.ChildByFieldName("key")
+						edits = append(edits, textEdit{
+							Start: key.StartByte(),
+							End:   key.StartByte() + uint32(len(rename.name)),
+							Text:  rename.newName,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return edits
+}
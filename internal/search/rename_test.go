@@ -0,0 +1,329 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRenameProject writes the test project with a module defining a struct and a
+// nested module.
+func writeRenameProject(t *testing.T) string {
+	dir := writeTestProject(t)
+
+	user := strings.Join([]string{
+		"defmodule TestApp.Accounts.User do",
+		"  @behaviour TestApp.Accounts.User.Nested",
+		"",
+		"  defmodule Nested do",
+		"  end",
+		"",
+		"  def new, do: %TestApp.Accounts.User{}",
+		"  def nested, do: :\"Elixir.TestApp.Accounts.User.Nested\".new()",
+		"end",
+		"",
+	}, "\n")
+	addProjectFiles(t, dir, map[string]string{"user.ex": user})
+	return dir
+}
+
+func TestRenameModuleFiles(t *testing.T) {
+	dir := writeRenameProject(t)
+
+	tests := []struct {
+		to       string
+		expected map[string][]string // the lines changed in each file
+	}{
+		// the alias stays in the group
+		{
+			"TestApp.Accounts.Member",
+			map[string][]string{
+				"user.ex": {
+					"-defmodule TestApp.Accounts.User do",
+					"-  @behaviour TestApp.Accounts.User.Nested",
+					"+defmodule TestApp.Accounts.Member do",
+					"+  @behaviour TestApp.Accounts.Member.Nested",
+					"-  def new, do: %TestApp.Accounts.User{}",
+					"-  def nested, do: :\"Elixir.TestApp.Accounts.User.Nested\".new()",
+					"+  def new, do: %TestApp.Accounts.Member{}",
+					"+  def nested, do: :\"Elixir.TestApp.Accounts.Member.Nested\".new()",
+				},
+				"users.ex": {
+					"-  alias TestApp.Accounts.{User, Admin}",
+					"+  alias TestApp.Accounts.{Member, Admin}",
+					"-  def get_user!(id), do: Repo.get!(User, id)",
+					"+  def get_user!(id), do: Repo.get!(Member, id)",
+					"-    Repo.get_by(User, username: username)",
+					"+    Repo.get_by(Member, username: username)",
+					"-    |> User.changeset(attrs)",
+					"+    |> Member.changeset(attrs)",
+				},
+			},
+		},
+		// the alias is split out of the group and keeps its name
+		{
+			"TestApp.People.User",
+			map[string][]string{
+				"user.ex": {
+					"-defmodule TestApp.Accounts.User do",
+					"-  @behaviour TestApp.Accounts.User.Nested",
+					"+defmodule TestApp.People.User do",
+					"+  @behaviour TestApp.People.User.Nested",
+					"-  def new, do: %TestApp.Accounts.User{}",
+					"-  def nested, do: :\"Elixir.TestApp.Accounts.User.Nested\".new()",
+					"+  def new, do: %TestApp.People.User{}",
+					"+  def nested, do: :\"Elixir.TestApp.People.User.Nested\".new()",
+				},
+				"users.ex": {
+					"-  alias TestApp.Accounts.{User, Admin}",
+					"+  alias TestApp.Accounts.Admin",
+					"+  alias TestApp.People.User",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		changes, err := renameModuleFiles(&RenameInput{Dir: dir, From: "TestApp.Accounts.User", To: test.to})
+		if err != nil {
+			t.Fatalf("rename failed: %v", err)
+		}
+
+		got := map[string][]string{}
+		for _, change := range changes {
+			got[change.Path] = changedLines(change)
+		}
+
+		for path, lines := range test.expected {
+			if strings.Join(got[path], "\n") != strings.Join(lines, "\n") {
+				t.Errorf("%s %s: got\n%s\nwant\n%s", test.to, path, strings.Join(got[path], "\n"), strings.Join(lines, "\n"))
+			}
+		}
+
+		if len(got) != len(test.expected) {
+			t.Errorf("%s: got changes to %d files want %d", test.to, len(got), len(test.expected))
+		}
+	}
+}
+
+func TestRenameModuleErrors(t *testing.T) {
+	dir := writeRenameProject(t)
+
+	tests := []struct {
+		from string
+		to   string
+	}{
+		{"TestApp.Missing", "TestApp.Other"},
+		{"TestApp.Accounts.User", "not_a_module"},
+		{"TestApp.Accounts.User.Nested", "TestApp.Nested"},
+	}
+
+	for _, test := range tests {
+		if _, err := renameModuleFiles(&RenameInput{Dir: dir, From: test.from, To: test.to}); err == nil {
+			t.Errorf("%s -> %s: expected an error", test.from, test.to)
+		}
+	}
+}
+
+func TestRenameModuleMove(t *testing.T) {
+	dir := writeRenameProject(t)
+
+	if err := os.MkdirAll(filepath.Join(dir, "lib", "test_app", "accounts"), 0o755); err != nil {
+		t.Fatalf("Unable to create dir, %v", err)
+	}
+	if err := os.Rename(filepath.Join(dir, "users.ex"), filepath.Join(dir, "lib", "test_app", "accounts", "users.ex")); err != nil {
+		t.Fatalf("Unable to move file, %v", err)
+	}
+
+	input := &RenameInput{Dir: dir, From: "TestApp.Accounts.Users", To: "TestApp.Accounts.UserAPI", Move: true}
+	if err := RenameModule(input); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+
+	contents := string(mustRead(t, filepath.Join(dir, "lib", "test_app", "accounts", "user_api.ex")))
+	if !strings.HasPrefix(contents, "defmodule TestApp.Accounts.UserAPI do\n") {
+		t.Errorf("the module wasn't renamed, got\n%s", contents)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "lib", "test_app", "accounts", "users.ex")); !os.IsNotExist(err) {
+		t.Errorf("users.ex should have been moved, got %v", err)
+	}
+
+	contents = string(mustRead(t, filepath.Join(dir, "accounts.ex")))
+	if !strings.Contains(contents, "&TestApp.Accounts.UserAPI.get_user!/1") {
+		t.Errorf("accounts.ex wasn't updated, got\n%s", contents)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	tests := map[string]string{
+		"MyApp.Users":      "my_app/users",
+		"MyApp.HTTPClient": "my_app/http_client",
+		"MyAPI":            "my_api",
+		"Base64Encoder":    "base64_encoder",
+	}
+
+	for module, expected := range tests {
+		if got := modulePath(module); got != expected {
+			t.Errorf("%s: got %s want %s", module, got, expected)
+		}
+	}
+}
+
+func TestRenameFunctionFiles(t *testing.T) {
+	dir := writeTestProject(t)
+
+	other := strings.Join([]string{
+		"defmodule TestApp.Other do",
+		"  alias TestApp.Accounts.Users",
+		"",
+		"  @spec go(integer) :: any",
+		"  def go(id, opts \\\\ []), do: Users.get_user!(id) && Users.get_user!(id, opts)",
+		"",
+		"  def go(id, opts, extra), do: go(id) && go(id, opts)",
+		"",
+		"  defdelegate get(id), to: Users",
+		"end",
+		"",
+	}, "\n")
+	addProjectFiles(t, dir, map[string]string{"other.ex": other})
+
+	tests := []struct {
+		mfa      string
+		name     string
+		expected map[string][]string // the lines changed in each file
+	}{
+		{
+			"TestApp.Accounts.Users.get_user!/1",
+			"find_user!",
+			map[string][]string{
+				"accounts.ex": {
+					"-  import TestApp.Accounts.Users, only: [get_user!: 1]",
+					"+  import TestApp.Accounts.Users, only: [find_user!: 1]",
+					"-  defdelegate fetch_user!(id), to: TestApp.Accounts.Users, as: :get_user!",
+					"+  defdelegate fetch_user!(id), to: TestApp.Accounts.Users, as: :find_user!",
+					"-  def load(ids), do: Enum.map(ids, &TestApp.Accounts.Users.get_user!/1)",
+					"+  def load(ids), do: Enum.map(ids, &TestApp.Accounts.Users.find_user!/1)",
+					"-    |> get_user!()",
+					"+    |> find_user!()",
+					"-  def fetch(id), do: get_user!(id)",
+					"+  def fetch(id), do: find_user!(id)",
+				},
+				"users.ex": {
+					"-  def get_user!(id), do: Repo.get!(User, id)",
+					"+  def find_user!(id), do: Repo.get!(User, id)",
+				},
+				// the call with another arity is left alone
+				"other.ex": {
+					"-  def go(id, opts \\\\ []), do: Users.get_user!(id) && Users.get_user!(id, opts)",
+					"+  def go(id, opts \\\\ []), do: Users.find_user!(id) && Users.get_user!(id, opts)",
+				},
+			},
+		},
+		// the default argument defines go/1 as well as go/2, but not go/3
+		{
+			"TestApp.Other.go/1",
+			"run",
+			map[string][]string{
+				"other.ex": {
+					"-  @spec go(integer) :: any",
+					"-  def go(id, opts \\\\ []), do: Users.get_user!(id) && Users.get_user!(id, opts)",
+					"+  @spec run(integer) :: any",
+					"+  def run(id, opts \\\\ []), do: Users.get_user!(id) && Users.get_user!(id, opts)",
+					"-  def go(id, opts, extra), do: go(id) && go(id, opts)",
+					"+  def go(id, opts, extra), do: run(id) && run(id, opts)",
+				},
+			},
+		},
+		// the delegate keeps delegating to the function with the old name
+		{
+			"TestApp.Other.get/1",
+			"fetch",
+			map[string][]string{
+				"other.ex": {
+					"-  defdelegate get(id), to: Users",
+					"+  defdelegate fetch(id), to: Users, as: :get",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		changes, err := renameFunctionFiles(&RenameInput{Dir: dir, From: test.mfa, To: test.name})
+		if err != nil {
+			t.Fatalf("rename failed: %v", err)
+		}
+
+		got := map[string][]string{}
+		for _, change := range changes {
+			got[change.Path] = changedLines(change)
+		}
+
+		for path, lines := range test.expected {
+			if strings.Join(got[path], "\n") != strings.Join(lines, "\n") {
+				t.Errorf("%s %s: got\n%s\nwant\n%s", test.mfa, path, strings.Join(got[path], "\n"), strings.Join(lines, "\n"))
+			}
+		}
+
+		if len(got) != len(test.expected) {
+			t.Errorf("%s: got changes to %d files want %d", test.mfa, len(got), len(test.expected))
+		}
+	}
+}
+
+func TestRenameFunctionErrors(t *testing.T) {
+	dir := writeTestProject(t)
+
+	tests := []struct {
+		mfa  string
+		name string
+	}{
+		{"TestApp.Accounts.Users.get_user!", "find_user!"},
+		{"TestApp.Accounts.Users.get_user!/2", "find_user!"},
+		{"TestApp.Accounts.Users.get_user!/1", "Find"},
+		{"TestApp.Accounts.Users.get_user!/1", "get_by_username"},
+	}
+
+	for _, test := range tests {
+		if _, err := renameFunctionFiles(&RenameInput{Dir: dir, From: test.mfa, To: test.name}); err == nil {
+			t.Errorf("%s -> %s: expected an error", test.mfa, test.name)
+		}
+	}
+}
+
+func TestRenameFunctionConfirm(t *testing.T) {
+	dir := writeTestProject(t)
+	before := string(mustRead(t, filepath.Join(dir, "users.ex")))
+
+	input := &RenameInput{Dir: dir, From: "TestApp.Accounts.Users.get_user!/1", To: "find_user!", Confirm: func() bool { return false }}
+	if err := RenameFunction(input); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+
+	if string(mustRead(t, filepath.Join(dir, "users.ex"))) != before {
+		t.Errorf("users.ex was written without being confirmed")
+	}
+
+	input.Confirm = func() bool { return true }
+	if err := RenameFunction(input); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+
+	if !strings.Contains(string(mustRead(t, filepath.Join(dir, "users.ex"))), "def find_user!(id)") {
+		t.Errorf("users.ex wasn't written once confirmed")
+	}
+}
+
+// changedLines returns the removed and added lines of a change's diff
+func changedLines(change fileChange) []string {
+	lines := []string{}
+	for _, line := range strings.Split(change.diff(), "\n") {
+		if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") ||
+			strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Rewrite replaces code matching a pattern with a replacement template in every elixir
+// file under dir. The template can use the metavariables of the pattern, eg. rewriting
+// Repo.get!($S, $I) to Repo.fetch!($S, $I). With dryRun the changes are printed as a
+// unified diff instead of being written.
+func Rewrite(dir string, pattern string, replacement string, dryRun bool) error {
+	changes, err := rewriteFiles(dir, pattern, replacement)
+	if err != nil {
+		return err
+	}
+
+	return applyChanges(dir, changes, dryRun)
+}
+
+// rewriteFiles finds the changes a rewrite makes without writing them. Only the matched
+// code changes, so the formatting around it is kept.
+func rewriteFiles(dir string, pattern string, replacement string) ([]fileChange, error) {
+	compiled, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match := range metavarRegex.FindAllStringSubmatch(replacement, -1) {
+		if match[1] == "_" || !slices.Contains(compiled.names, match[1]) {
+			return nil, fmt.Errorf("the replacement uses $%s which isn't bound by the pattern", match[1])
+		}
+	}
+
+	changes := []fileChange{}
+	err = walkElixirFiles(dir, func(path string) error {
+		root, contents, err := parseFile(path)
+		if err != nil {
+			return err
+		}
+
+		matches, err := findPatternMatches(root, contents, compiled)
+		if err != nil {
+			return err
+		}
+
+		if len(matches) == 0 {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		after := rewriteCode(contents, 0, uint32(len(contents)), matches, replacement)
+		changes = append(changes, fileChange{Path: relPath, Before: contents, After: []byte(after)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// rewriteCode returns the code from start up to end with the matches in it replaced by the
+// template. Matches nested in another match are rewritten first and then filled in to the
+// outer match's template, so Repo.get!(S, Repo.get!(T, id)) is rewritten in one run.
+func rewriteCode(contents []byte, start uint32, end uint32, matches []PatternMatch, template string) string {
+	edits := []textEdit{}
+	for _, match := range matches {
+		if match.node.StartByte() < start || match.node.EndByte() > end {
+			continue
+		}
+
+		bindings := map[string]string{}
+		for _, binding := range match.Bindings {
+			bindings[binding.Name] = rewriteCode(contents, binding.node.StartByte(), binding.node.EndByte(), matches, template)
+		}
+
+		edits = append(edits, textEdit{
+			Start: match.node.StartByte() - start,
+			End:   match.node.EndByte() - start,
+			Text:  expandTemplate(template, match, bindings, contents),
+		})
+	}
+
+	// edits for the nested matches overlap the outer match's edit and are dropped
+	return string(applyEdits(contents[start:end], edits))
+}
+
+// expandTemplate fills in the metavariables of a replacement with the code bound to them.
+// Lines after the first are indented to line up with the matched code.
+func expandTemplate(template string, match PatternMatch, bindings map[string]string, contents []byte) string {
+	lineStart := strings.LastIndexByte(string(contents[:match.node.StartByte()]), '\n') + 1
+	line := string(contents[lineStart:match.node.StartByte()])
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+	lines := strings.Split(template, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+
+	return metavarRegex.ReplaceAllStringFunc(strings.Join(lines, "\n"), func(metavar string) string {
+		return bindings[metavar[1:]]
+	})
+}
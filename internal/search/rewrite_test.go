@@ -0,0 +1,92 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteFiles(t *testing.T) {
+	dir := writeTestProject(t)
+
+	changes, err := rewriteFiles(dir, "$X |> get_user!()", "get_user!(\n  $X\n)")
+	if err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Path != "accounts.ex" {
+		t.Fatalf("got %v want a change to accounts.ex", changes)
+	}
+
+	expected := strings.Join([]string{
+		"--- a/accounts.ex",
+		"+++ b/accounts.ex",
+		"@@ -6,8 +6,9 @@",
+		"   def load(ids), do: Enum.map(ids, &TestApp.Accounts.Users.get_user!/1)",
+		" ",
+		"   def load_one(id) do",
+		"-    id",
+		"-    |> get_user!()",
+		"+    get_user!(",
+		"+      id",
+		"+    )",
+		"   end",
+		" end",
+		" ",
+		"",
+	}, "\n")
+	if got := changes[0].diff(); got != expected {
+		t.Errorf("got\n%s\nwant\n%s", got, expected)
+	}
+
+	// nothing is written until the changes are
+	if string(mustRead(t, filepath.Join(dir, "accounts.ex"))) != string(changes[0].Before) {
+		t.Errorf("accounts.ex was written by a dry run")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	dir := writeTestProject(t)
+
+	// TestApp.Repo is aliased as Repo in users.ex
+	if err := Rewrite(dir, "TestApp.Repo.get!($S, $I)", "Repo.fetch!($S, $I)", false); err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+
+	contents := string(mustRead(t, filepath.Join(dir, "users.ex")))
+	if !strings.Contains(contents, "  def get_user!(id), do: Repo.fetch!(User, id)\n") {
+		t.Errorf("users.ex wasn't rewritten, got\n%s", contents)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("got %v %v want only the project files", entries, err)
+	}
+}
+
+func TestRewriteUnboundMetavar(t *testing.T) {
+	dir := writeTestProject(t)
+	for _, replacement := range []string{"Repo.fetch!($S, $ID)", "Repo.fetch!($_)"} {
+		if _, err := rewriteFiles(dir, "Repo.get!($S, $I)", replacement); err == nil {
+			t.Errorf("%s: expected an error for a metavariable the pattern doesn't bind", replacement)
+		}
+	}
+}
+
+func TestRewriteNested(t *testing.T) {
+	dir := writeProjectFiles(t, map[string]string{
+		"nested.ex": "defmodule Nested do\n  def load(id), do: Repo.get!(User, Repo.get!(Other, id))\nend\n",
+	})
+
+	changes, err := rewriteFiles(dir, "Repo.get!($S, $I)", "Repo.fetch!($S, $I)")
+	if err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+
+	// the inner match is rewritten along with the outer one
+	expected := "defmodule Nested do\n  def load(id), do: Repo.fetch!(User, Repo.fetch!(Other, id))\nend\n"
+	if len(changes) != 1 || string(changes[0].After) != expected {
+		t.Errorf("got %v want\n%s", changes, expected)
+	}
+}
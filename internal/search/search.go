@@ -7,6 +7,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sync"
 
 	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/smacker/go-tree-sitter/elixir"
@@ -23,14 +24,84 @@ const (
 	SearchTypeStr SearchType = iota
 	SearchTypeDoc
 	SearchTypeFnCall
+	SearchTypeImpl
+	SearchTypeProtocol
+	SearchTypeComment
+	SearchTypeTodo
+	SearchTypeDoctest
+	SearchTypeTest
+	SearchTypeQuery
+	SearchTypePattern
 )
 
+// searchTypes maps the names of the search modes to their search types.
+var searchTypes = map[string]SearchType{
+	"fncall":   SearchTypeFnCall,
+	"str":      SearchTypeStr,
+	"doc":      SearchTypeDoc,
+	"impl":     SearchTypeImpl,
+	"protocol": SearchTypeProtocol,
+	"comment":  SearchTypeComment,
+	"todo":     SearchTypeTodo,
+	"doctest":  SearchTypeDoctest,
+	"test":     SearchTypeTest,
+	"query":    SearchTypeQuery,
+	"pattern":  SearchTypePattern,
+}
+
+// ParseSearchType returns the search type for a search mode like fncall or str.
+func ParseSearchType(mode string) (SearchType, error) {
+	searchType, ok := searchTypes[mode]
+	if !ok {
+		return 0, fmt.Errorf("invalid search mode %s", mode)
+	}
+
+	return searchType, nil
+}
+
+// IsReport checks if the search can be run without search terms.
+func (input *SearchInput) IsReport() bool {
+	return input.SearchType == SearchTypeTodo || input.SearchType == SearchTypeTest ||
+		(input.SearchType == SearchTypeDoc && input.Undocumented)
+}
+
 // SearchInput holds all of the input necessary to perform a search. The only
 // optional input is Function.
 type SearchInput struct {
 	SearchTerms string
 	SearchType  SearchType
 	Dir         string
+
+	// Sigils limits string searches to sigils with these names, eg. r or H.
+	Sigils []string
+
+	// LiteralOnly ignores the code in string interpolations, Interpolations only
+	// searches that code and Charlists includes 'charlists' in string searches.
+	LiteralOnly    bool
+	Interpolations bool
+	Charlists      bool
+
+	// Undocumented changes doc searches to report public functions without docs.
+	Undocumented bool
+
+	// Doctests includes the iex> examples in docs when searching function calls.
+	Doctests bool
+
+	// Tags and Calls filter test searches to tests with all of the tags that make a
+	// function call matching Calls.
+	Tags  []string
+	Calls string
+
+	// callbacks maps behaviour modules found in Dir to their "name/arity" callbacks.
+	// It is only loaded for SearchTypeImpl.
+	callbacks map[string][]string
+
+	// query is the compiled SearchTerms of a SearchTypeQuery search, compiled once per
+	// search instead of for every file.
+	query *sitter.Query
+
+	// pattern is the compiled SearchTerms of a SearchTypePattern search
+	pattern *codePattern
 }
 
 // Match represents a match found in the elixir source code.
@@ -43,10 +114,130 @@ type ResultsFormatter interface {
 	Format() string
 }
 
+// FileResults holds the formatted matches found in a single file.
+type FileResults struct {
+	Path    string // The path of the file relative to the searched directory
+	Matches []string
+}
+
 // Search performs a search and prints results to stdout
 func Search(input *SearchInput) error {
+	// use the on disk index to avoid parsing files when the project has one
+	indexed, err := indexedFiles(input.Dir)
+	if err != nil {
+		return err
+	}
+
+	results, err := searchFiles(input, indexed)
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		fmt.Println(res.Path)
+		for _, match := range res.Matches {
+			fmt.Println(match)
+		}
+		fmt.Println("")
+	}
+
+	return nil
+}
+
+// searchFiles searches every file under the input directory, answering the search from
+// the indexed files when possible. Only files with matches are returned.
+func searchFiles(input *SearchInput, indexed map[string]*FileIndex) ([]FileResults, error) {
+	results := []FileResults{}
+	err := streamSearch(context.Background(), input, indexed, func(res FileResults) error {
+		results = append(results, res)
+		return nil
+	})
+
+	return results, err
+}
+
+// streamSearch is searchFiles calling fn with the matches of each file as soon as the file
+// has been searched. The search stops early when ctx is done.
+func streamSearch(ctx context.Context, input *SearchInput, indexed map[string]*FileIndex, fn func(FileResults) error) error {
+	// some search types need to know about definitions in other files
+	if input.SearchType == SearchTypeImpl {
+		callbacks, err := parseProjectCallbacks(input.Dir)
+		if err != nil {
+			return err
+		}
+		input.callbacks = callbacks
+	}
+
+	if input.SearchType == SearchTypeQuery && input.query == nil {
+		query, err := compileUserQuery(input.SearchTerms)
+		if err != nil {
+			return err
+		}
+		input.query = query
+	}
+
+	if input.SearchType == SearchTypePattern && input.pattern == nil {
+		pattern, err := compilePattern(input.SearchTerms)
+		if err != nil {
+			return err
+		}
+		input.pattern = pattern
+	}
+
 	// get all files to search
-	return filepath.WalkDir(input.Dir, func(path string, entry fs.DirEntry, walkErr error) error {
+	return walkElixirFiles(input.Dir, func(path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		res, ok := []string{}, false
+		if file := indexed[path]; file != nil {
+			res, ok = searchIndex(file, input)
+		}
+
+		if !ok {
+			var err error
+			res, err = searchFile(path, input)
+			if err != nil {
+				return err
+			}
+		}
+
+		relFile, err := filepath.Rel(input.Dir, path)
+		if err != nil {
+			return err
+		}
+
+		if len(res) > 0 {
+			return fn(FileResults{Path: relFile, Matches: res})
+		}
+
+		return nil
+	})
+}
+
+// compiling a query is much slower than running it, so each query is only compiled once
+var queryCache sync.Map
+
+// newQuery compiles one of the embedded queries for the elixir grammar, reusing the query
+// if it has already been compiled. Queries from users are compiled without the cache.
+func newQuery(source string) (*sitter.Query, error) {
+	if query, ok := queryCache.Load(source); ok {
+		return query.(*sitter.Query), nil
+	}
+
+	query, err := sitter.NewQuery([]byte(source), elixir.GetLanguage())
+	if err != nil {
+		return nil, err
+	}
+
+	queryCache.Store(source, query)
+	return query, nil
+}
+
+// walkElixirFiles calls fn for every elixir file found recursively under dir.
+func walkElixirFiles(dir string, fn func(path string) error) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
@@ -55,23 +246,7 @@ func Search(input *SearchInput) error {
 		if entry.Type().IsRegular() {
 			ext := filepath.Ext(entry.Name())
 			if ext == ".ex" || ext == ".exs" {
-				res, err := searchFile(path, input)
-				if err != nil {
-					return err
-				}
-
-				relFile, err := filepath.Rel(input.Dir, path)
-				if err != nil {
-					return err
-				}
-
-				if len(res) > 0 {
-					fmt.Println(relFile)
-					for _, match := range res {
-						fmt.Println(match)
-					}
-					fmt.Println("")
-				}
+				return fn(path)
 			}
 		}
 
@@ -79,21 +254,26 @@ func Search(input *SearchInput) error {
 	})
 }
 
-func searchFile(file string, input *SearchInput) ([]string, error) {
-	lang := elixir.GetLanguage()
-
-	// setup the parser
-	parser := sitter.NewParser()
-	parser.SetLanguage(lang)
-
+// parseFile reads and parses a single elixir file, returning the root node and the
+// file contents.
+func parseFile(file string) (*sitter.Node, []byte, error) {
 	// read the file
 	contents, err := os.ReadFile(file)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// get the root node to start searching from
-	root, err := sitter.ParseCtx(context.Background(), contents, lang)
+	root, err := sitter.ParseCtx(context.Background(), contents, elixir.GetLanguage())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return root, contents, nil
+}
+
+func searchFile(file string, input *SearchInput) ([]string, error) {
+	root, contents, err := parseFile(file)
 	if err != nil {
 		return nil, err
 	}
@@ -105,15 +285,35 @@ func searchFile(file string, input *SearchInput) ([]string, error) {
 	case SearchTypeStr:
 		searchResults, searchErr = searchStr(root, contents, input)
 	case SearchTypeDoc:
-		searchResults, searchErr = searchDoc(root, contents, input)
+		if input.Undocumented {
+			searchResults, searchErr = searchUndocumented(root, contents, input)
+		} else {
+			searchResults, searchErr = searchDoc(root, contents, input)
+		}
 	case SearchTypeFnCall:
 		searchResults, searchErr = searchFnCalls(root, contents, input)
+	case SearchTypeImpl:
+		searchResults, searchErr = searchImpls(root, contents, input)
+	case SearchTypeProtocol:
+		searchResults, searchErr = searchProtocols(root, contents, input)
+	case SearchTypeComment:
+		searchResults, searchErr = searchComments(root, contents, input)
+	case SearchTypeTodo:
+		searchResults, searchErr = searchTodos(root, contents, input)
+	case SearchTypeDoctest:
+		searchResults, searchErr = searchDoctests(root, contents, input)
+	case SearchTypeTest:
+		searchResults, searchErr = searchTestCases(root, contents, input)
+	case SearchTypeQuery:
+		searchResults, searchErr = searchQuery(root, contents, input)
+	case SearchTypePattern:
+		searchResults, searchErr = searchPattern(root, contents, input)
 	default:
 		return nil, fmt.Errorf("Invalid search type: %d", input.SearchType)
 	}
 
 	if searchErr != nil {
-		return nil, err
+		return nil, searchErr
 	}
 
 	output := []string{}
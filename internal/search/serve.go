@@ -0,0 +1,345 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+)
+
+// searchParams are the params of a search request. Mode is any of the search modes, and the
+// rest match the search flags.
+type searchParams struct {
+	Mode           string   `json:"mode"`
+	Query          string   `json:"query"`
+	Sigils         []string `json:"sigils"`
+	LiteralOnly    bool     `json:"literalOnly"`
+	Interpolations bool     `json:"interpolations"`
+	Charlists      bool     `json:"charlists"`
+	Undocumented   bool     `json:"undocumented"`
+	Doctests       bool     `json:"doctests"`
+	Tags           []string `json:"tags"`
+	Calls          string   `json:"calls"`
+}
+
+// searchInput checks the params the same way as the command line and builds the input
+// for searching dir.
+func (p searchParams) searchInput(dir string) (*SearchInput, error) {
+	searchType, err := ParseSearchType(p.Mode)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	if p.LiteralOnly && p.Interpolations {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "literalOnly and interpolations can't be used together"}
+	}
+
+	input := &SearchInput{
+		SearchTerms:    p.Query,
+		SearchType:     searchType,
+		Dir:            dir,
+		Sigils:         p.Sigils,
+		LiteralOnly:    p.LiteralOnly,
+		Interpolations: p.Interpolations,
+		Charlists:      p.Charlists,
+		Undocumented:   p.Undocumented,
+		Doctests:       p.Doctests,
+		Tags:           p.Tags,
+		Calls:          p.Calls,
+	}
+	if input.SearchTerms == "" && !input.IsReport() {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "empty query"}
+	}
+
+	return input, nil
+}
+
+// serveSummary is the result of a request that streamed its results
+type serveSummary struct {
+	Files   int `json:"files"`
+	Matches int `json:"matches"`
+}
+
+// liveIndex is an in memory index of a project shared by concurrent requests. Each request
+// brings the index up to date with refresh, then holds a read lock while using it.
+type liveIndex struct {
+	dir     string
+	watcher *watcher
+	mu      sync.RWMutex // guards the watcher, which is written to by refresh
+}
+
+// newLiveIndex indexes a project. The on disk index is only kept up to date when the
+// project already has one.
+func newLiveIndex(dir string) (*liveIndex, error) {
+	index, err := loadIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newWatcher(dir, index != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &liveIndex{dir: dir, watcher: w}, nil
+}
+
+// refresh indexes files that changed on disk
+func (l *liveIndex) refresh() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.watcher.update()
+	return err
+}
+
+// path resolves a path given in a request against the project directory
+func (l *liveIndex) path(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(l.dir, path)
+}
+
+// rpcServer answers JSON-RPC requests from a live index of the project. Requests are
+// handled concurrently.
+type rpcServer struct {
+	*liveIndex
+	conn *rpcConn
+
+	cancelsMu sync.Mutex
+	cancels   map[string]context.CancelFunc // keyed by request id
+}
+
+// Serve runs a JSON-RPC 2.0 server for the project in dir, reading one request per line
+// from in and writing one response or notification per line to out until in is closed or
+// ctx is done.
+//
+// The search and refs methods stream their results as search/result and refs/result
+// notifications, one per file, before responding with a count of the results. Any
+// request can be cancelled with a $/cancelRequest notification giving its id.
+func Serve(ctx context.Context, dir string, in io.Reader, out io.Writer) error {
+	index, err := newLiveIndex(dir)
+	if err != nil {
+		return err
+	}
+
+	server := &rpcServer{
+		liveIndex: index,
+		conn:      newLineRPCConn(in, out),
+		cancels:   map[string]context.CancelFunc{},
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		req, err := server.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if err := server.conn.reply(json.RawMessage("null"), nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if req.Method == "$/cancelRequest" {
+			params := struct {
+				ID json.RawMessage `json:"id"`
+			}{}
+			if unmarshalParams(req, &params) == nil {
+				server.cancel(params.ID)
+			}
+			continue
+		}
+
+		// there aren't any other notifications
+		if req.isNotification() {
+			continue
+		}
+
+		reqCtx := server.track(ctx, req.ID)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer server.cancel(req.ID)
+
+			result, err := server.handle(reqCtx, req)
+			if reqCtx.Err() != nil && ctx.Err() == nil {
+				result, err = nil, &rpcError{Code: rpcRequestCancelled, Message: "request cancelled"}
+			}
+
+			server.conn.reply(req.ID, result, err)
+		}()
+	}
+}
+
+// track creates a context for a request that is cancelled by $/cancelRequest
+func (s *rpcServer) track(ctx context.Context, id json.RawMessage) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.cancelsMu.Lock()
+	defer s.cancelsMu.Unlock()
+	s.cancels[string(id)] = cancel
+
+	return ctx
+}
+
+func (s *rpcServer) cancel(id json.RawMessage) {
+	s.cancelsMu.Lock()
+	defer s.cancelsMu.Unlock()
+
+	if cancel, ok := s.cancels[string(id)]; ok {
+		cancel()
+		delete(s.cancels, string(id))
+	}
+}
+
+func (s *rpcServer) handle(ctx context.Context, req *rpcRequest) (any, error) {
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch req.Method {
+	case "search":
+		params := searchParams{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.search(ctx, req.ID, params)
+	case "refs":
+		params := struct {
+			MFA string `json:"mfa"`
+		}{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.refs(ctx, req.ID, params.MFA)
+	case "outline":
+		params := struct {
+			Path string `json:"path"`
+		}{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.outline(params.Path)
+	case "definition":
+		params := struct {
+			Path   string `json:"path"`
+			Line   uint32 `json:"line"`
+			Column uint32 `json:"column"`
+		}{}
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params.Path, params.Line, params.Column)
+	}
+
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+func (s *rpcServer) search(ctx context.Context, id json.RawMessage, params searchParams) (any, error) {
+	input, err := params.searchInput(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := serveSummary{}
+	err = streamSearch(ctx, input, s.watcher.files, func(res FileResults) error {
+		summary.Files++
+		summary.Matches += len(res.Matches)
+
+		return s.conn.notify("search/result", map[string]any{
+			"id":      id,
+			"path":    res.Path,
+			"matches": res.Matches,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (s *rpcServer) refs(ctx context.Context, id json.RawMessage, mfa string) (any, error) {
+	files := s.watcher.indexedFiles()
+	found, err := findRefs(files, mfa)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+
+	summary := serveSummary{}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		refs := found[file.Path]
+		if len(refs) == 0 {
+			continue
+		}
+
+		summary.Files++
+		summary.Matches += len(refs)
+
+		err := s.conn.notify("refs/result", map[string]any{
+			"id":   id,
+			"path": s.watcher.relPath(file.Path),
+			"refs": refs,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
+
+func (s *rpcServer) outline(path string) ([]Symbol, error) {
+	root, contents, err := parseFile(s.path(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseOutline(root, contents)
+}
+
+// definition finds where the symbol at a 1 based line and column is defined. Nothing being
+// found isn't an error.
+func (s *rpcServer) definition(path string, line uint32, column uint32) ([]Location, error) {
+	if line == 0 || column == 0 {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "line and column start at 1"}
+	}
+
+	root, contents, err := parseFile(s.path(path))
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := definitionAt(s.watcher.indexedFiles(), s.path(path), root, contents, line, column)
+	if err != nil {
+		return []Location{}, nil
+	}
+
+	for i, location := range locations {
+		locations[i].Path = s.watcher.relPath(location.Path)
+	}
+
+	return locations, nil
+}
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type serveMessage struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// runServe sends one request per line to the server and returns every message it writes
+func runServe(t *testing.T, dir string, requests ...string) []serveMessage {
+	out := &strings.Builder{}
+	if err := Serve(context.Background(), dir, strings.NewReader(strings.Join(requests, "\n")), out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	messages := []serveMessage{}
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		msg := serveMessage{}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Unable to unmarshal message %s, %v", scanner.Text(), err)
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages
+}
+
+func TestServe(t *testing.T) {
+	dir := writeTestProject(t)
+	messages := runServe(t, dir,
+		`{"jsonrpc": "2.0", "id": 1, "method": "search", "params": {"mode": "fncall", "query": "Repo.get"}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "refs", "params": {"mfa": "TestApp.Accounts.Users.get_user!/1"}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "outline", "params": {"path": "accounts.ex"}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "definition", "params": {"path": "accounts.ex", "line": 17, "column": 22}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "search", "params": {"mode": "nope", "query": "x"}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "nope"}`,
+		`not json`,
+	)
+
+	results := map[int]json.RawMessage{}
+	errs := map[int]*rpcError{}
+	streamed := map[string][]string{}
+	for _, msg := range messages {
+		if msg.Method != "" {
+			params := struct {
+				ID      int               `json:"id"`
+				Path    string            `json:"path"`
+				Matches []string          `json:"matches"`
+				Refs    []json.RawMessage `json:"refs"`
+			}{}
+			json.Unmarshal(msg.Params, &params)
+			streamed[msg.Method] = append(streamed[msg.Method], params.Path)
+			continue
+		}
+
+		results[msg.ID] = msg.Result
+		errs[msg.ID] = msg.Error
+	}
+
+	if !reflect.DeepEqual(streamed["search/result"], []string{"users.ex"}) || string(results[1]) != `{"files":1,"matches":2}` {
+		t.Errorf("got search results %v %s", streamed["search/result"], results[1])
+	}
+
+	if !reflect.DeepEqual(streamed["refs/result"], []string{"accounts.ex"}) || string(results[2]) != `{"files":1,"matches":4}` {
+		t.Errorf("got refs results %v %s", streamed["refs/result"], results[2])
+	}
+
+	outline := []Symbol{}
+	json.Unmarshal(results[3], &outline)
+	if len(outline) != 2 || outline[1].Name != "TestApp.Accounts.Admin" || outline[1].Children[0].Name != "fetch/1" {
+		t.Errorf("got outline %s", results[3])
+	}
+
+	expected := `[{"path":"users.ex","line":13,"column":3,"contents":"def get_user!(id)"}]`
+	if string(results[4]) != expected {
+		t.Errorf("got definition %s want %s", results[4], expected)
+	}
+
+	if errs[5] == nil || errs[5].Code != rpcInvalidParams {
+		t.Errorf("got %v want invalid params", errs[5])
+	}
+
+	if errs[6] == nil || errs[6].Code != rpcMethodNotFound {
+		t.Errorf("got %v want method not found", errs[6])
+	}
+
+	if errs[0] == nil || errs[0].Code != rpcParseError {
+		t.Errorf("got %v want parse error", errs[0])
+	}
+}
+
+func TestServeCancel(t *testing.T) {
+	dir := writeTestProject(t)
+	index, err := newLiveIndex(dir)
+	if err != nil {
+		t.Fatalf("new live index failed: %v", err)
+	}
+
+	out := &strings.Builder{}
+	server := &rpcServer{liveIndex: index, conn: newLineRPCConn(strings.NewReader(""), out)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := &rpcRequest{ID: json.RawMessage("1"), Method: "search", Params: json.RawMessage(`{"mode": "str", "query": "Hello"}`)}
+	if _, err := server.handle(ctx, req); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v want the search to be cancelled", err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("expected no results to be streamed, got %s", out)
+	}
+}
@@ -3,26 +3,35 @@ package search
 import (
 	_ "embed"
 	"fmt"
+	"slices"
 	"strings"
 
 	sitter "github.com/smacker/go-tree-sitter"
-	"github.com/smacker/go-tree-sitter/elixir"
 )
 
 type Str struct {
-	Line     uint32
-	Contents string
+	Line      uint32
+	Contents  string
+	Sigil     string // The sigil name when the string is a sigil, eg. r for ~r/regex/
+	Modifiers string // The sigil modifiers, eg. i for ~r/regex/i
 }
 
 func (s Str) Format() string {
+	if s.Sigil != "" && s.Modifiers != "" {
+		return fmt.Sprintf("%d:[~%s %s] %s", s.Line, s.Sigil, s.Modifiers, s.Contents)
+	} else if s.Sigil != "" {
+		return fmt.Sprintf("%d:[~%s] %s", s.Line, s.Sigil, s.Contents)
+	}
+
 	return fmt.Sprintf("%d:%s", s.Line, s.Contents)
 }
 
 //go:embed queries/string_search.scm
 var strSearchQuery string
 
-func searchStr(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
-	query, err := sitter.NewQuery([]byte(strSearchQuery), elixir.GetLanguage())
+// Generate a list of the contents of every string, sigil and charlist
+func parseStrings(root *sitter.Node, contents []byte) ([]string, error) {
+	query, err := newQuery(strSearchQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -30,7 +39,7 @@ func searchStr(root *sitter.Node, contents []byte, input *SearchInput) ([]Result
 	cursor := sitter.NewQueryCursor()
 	cursor.Exec(query, root)
 
-	matches := []ResultsFormatter{}
+	strs := []string{}
 	for {
 		// get the match and break out if we're done matching
 		match, ok := cursor.NextMatch()
@@ -38,25 +47,16 @@ func searchStr(root *sitter.Node, contents []byte, input *SearchInput) ([]Result
 			break
 		}
 
-		match = cursor.FilterPredicates(match, contents)
 		for _, capture := range match.Captures {
-			lines := strings.Split(capture.Node.Content(contents), "\n")
-			for i, line := range lines {
-				if !isDoc(capture.Node, contents) && strings.Contains(line, input.SearchTerms) {
-					matches = append(matches, Str{
-						Contents: line,
-						Line:     capture.Node.StartPoint().Row + uint32(i),
-					})
-				}
-			}
+			strs = append(strs, capture.Node.Content(contents))
 		}
 	}
 
-	return matches, nil
+	return strs, nil
 }
 
-func searchDoc(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
-	query, err := sitter.NewQuery([]byte(strSearchQuery), elixir.GetLanguage())
+func searchStr(root *sitter.Node, contents []byte, input *SearchInput) ([]ResultsFormatter, error) {
+	query, err := newQuery(strSearchQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -74,12 +74,24 @@ func searchDoc(root *sitter.Node, contents []byte, input *SearchInput) ([]Result
 
 		match = cursor.FilterPredicates(match, contents)
 		for _, capture := range match.Captures {
+			if capture.Node.Type() == "charlist" && !input.Charlists {
+				continue
+			}
+
+			sigil, modifiers := sigilInfo(capture.Node, contents)
+			if len(input.Sigils) > 0 && !slices.Contains(input.Sigils, sigil) {
+				continue
+			}
+
 			lines := strings.Split(capture.Node.Content(contents), "\n")
+			searchable := strings.Split(searchableContent(capture.Node, contents, input), "\n")
 			for i, line := range lines {
-				if isDoc(capture.Node, contents) && strings.Contains(line, input.SearchTerms) {
+				if !isDoc(capture.Node, contents) && strings.Contains(searchable[i], input.SearchTerms) {
 					matches = append(matches, Str{
-						Contents: line,
-						Line:     capture.Node.StartPoint().Row + uint32(i),
+						Contents:  line,
+						Line:      capture.Node.StartPoint().Row + uint32(i),
+						Sigil:     sigil,
+						Modifiers: modifiers,
 					})
 				}
 			}
@@ -89,16 +101,77 @@ func searchDoc(root *sitter.Node, contents []byte, input *SearchInput) ([]Result
 	return matches, nil
 }
 
+// get the content of a string node that should be searched. With LiteralOnly any
+// interpolations are masked out, and with Interpolations everything except the code
+// inside of interpolations is masked out. Masking keeps the newlines so the content
+// still lines up with the original.
+func searchableContent(node *sitter.Node, contents []byte, input *SearchInput) string {
+	if !input.LiteralOnly && !input.Interpolations {
+		return node.Content(contents)
+	}
+
+	start := node.StartByte()
+	content := []byte(node.Content(contents))
+	keep := make([]bool, len(content))
+	for i := range keep {
+		keep[i] = input.LiteralOnly
+	}
+
+	for i := range int(node.NamedChildCount()) {
+		child := node.NamedChild(i)
+		if child.Type() != "interpolation" {
+			continue
+		}
+
+		// the code inside of #{ and }
+		for b := child.StartByte() + 2; b < child.EndByte()-1; b++ {
+			keep[b-start] = input.Interpolations
+		}
+
+		// the #{ and } delimiters are never searched
+		for _, b := range []uint32{child.StartByte(), child.StartByte() + 1, child.EndByte() - 1} {
+			keep[b-start] = false
+		}
+	}
+
+	for i, c := range content {
+		if !keep[i] && c != '\n' {
+			content[i] = 0
+		}
+	}
+
+	return string(content)
+}
+
+// get the name and modifiers of a sigil node. Both are empty for plain strings.
+func sigilInfo(node *sitter.Node, contents []byte) (string, string) {
+	if node.Type() != "sigil" {
+		return "", ""
+	}
+
+	var name, modifiers string
+	for i := range int(node.NamedChildCount()) {
+		switch child := node.NamedChild(i); child.Type() {
+		case "sigil_name":
+			name = child.Content(contents)
+		case "sigil_modifiers":
+			modifiers = child.Content(contents)
+		}
+	}
+
+	return name, modifiers
+}
+
 // checks if the string is part of a documentation block
 func isDoc(node *sitter.Node, contents []byte) bool {
-	// follow the node up 2 parents. If the grandparent node is a doc or moduledoc
+	// follow the node up 2 parents. If the grandparent node is a doc attribute
 	// identifier then don't return a match.
 	isDoc := false
 
 	if parent := node.Parent(); parent != nil {
 		if grandparent := parent.Parent(); grandparent != nil {
 			target := grandparent.ChildByFieldName("target")
-			if target != nil && (target.Content(contents) == "doc" || target.Content(contents) == "moduledoc") {
+			if target != nil && slices.Contains(docAttributes, target.Content(contents)) {
 				isDoc = true
 			}
 		}
@@ -28,24 +28,144 @@ func TestSearchStr(t *testing.T) {
 	}
 }
 
-func TestSearchDoc(t *testing.T) {
-	root, contents := readTestFile(t)
+func TestSearchStrSigils(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/sigils.ex")
 	input := &SearchInput{
-		SearchType:  SearchTypeDoc,
+		SearchType:  SearchTypeStr,
 		SearchTerms: "user",
 	}
 
-	matches, err := searchDoc(root, contents, input)
+	matches, err := searchStr(root, contents, input)
+	if err != nil {
+		t.Errorf("search string failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Str{Contents: "~w(admin user guest)a", Line: 4, Sigil: "w", Modifiers: "a"},
+		Str{Contents: "    <span class=\"username\">{@user.username}</span>", Line: 8, Sigil: "H"},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %v want %v", matches, expected)
+	}
+}
+
+func TestSearchStrSigilFilter(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/sigils.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeStr,
+		SearchTerms: "a-z",
+		Sigils:      []string{"r", "H"},
+	}
+
+	matches, err := searchStr(root, contents, input)
+	if err != nil {
+		t.Errorf("search string failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Str{Contents: "~r/^[a-z_]+$/iu", Line: 3, Sigil: "r", Modifiers: "iu"},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %v want %v", matches, expected)
+	}
+}
+
+func TestSearchStrLiteralOnly(t *testing.T) {
+	root, contents := readTestFile(t)
+	input := &SearchInput{
+		SearchType:  SearchTypeStr,
+		SearchTerms: "color",
+		LiteralOnly: true,
+	}
+
+	matches, err := searchStr(root, contents, input)
 	if err != nil {
-		t.Errorf("search doc failed: %v", err)
+		t.Errorf("search string failed: %v", err)
 	}
 
 	expected := []ResultsFormatter{
-		Str{Contents: "  Get a user by id", Line: 10},
-		Str{Contents: "  Returns a hello message for the user as a string", Line: 26},
+		Str{Contents: "\"Hello #{user.first}, your favorite color is: #{color}\"", Line: 36},
 	}
 
 	if !reflect.DeepEqual(matches, expected) {
 		t.Errorf("got %v want %v", matches, expected)
 	}
+
+	// first only appears in the interpolation
+	input.SearchTerms = "first"
+	matches, err = searchStr(root, contents, input)
+	if err != nil {
+		t.Errorf("search string failed: %v", err)
+	}
+
+	if len(matches) != 0 {
+		t.Errorf("got %v want no matches", matches)
+	}
+}
+
+func TestSearchStrInterpolations(t *testing.T) {
+	root, contents := readTestFile(t)
+	input := &SearchInput{
+		SearchType:     SearchTypeStr,
+		SearchTerms:    "Hello",
+		Interpolations: true,
+	}
+
+	matches, err := searchStr(root, contents, input)
+	if err != nil {
+		t.Errorf("search string failed: %v", err)
+	}
+
+	if len(matches) != 0 {
+		t.Errorf("got %v want no matches", matches)
+	}
+
+	input.SearchTerms = "user.first"
+	matches, err = searchStr(root, contents, input)
+	if err != nil {
+		t.Errorf("search string failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Str{Contents: "\"Hello #{user.first}, your favorite color is: #{color}\"", Line: 36},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %v want %v", matches, expected)
+	}
+}
+
+func TestSearchStrCharlists(t *testing.T) {
+	root, contents := parseTestFile(t, "testdata/sigils.ex")
+	input := &SearchInput{
+		SearchType:  SearchTypeStr,
+		SearchTerms: "guest",
+		Charlists:   true,
+	}
+
+	matches, err := searchStr(root, contents, input)
+	if err != nil {
+		t.Errorf("search string failed: %v", err)
+	}
+
+	expected := []ResultsFormatter{
+		Str{Contents: "~w(admin user guest)a", Line: 4, Sigil: "w", Modifiers: "a"},
+		Str{Contents: "'guest'", Line: 14},
+	}
+
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("got %v want %v", matches, expected)
+	}
+
+	input.Charlists = false
+	matches, err = searchStr(root, contents, input)
+	if err != nil {
+		t.Errorf("search string failed: %v", err)
+	}
+
+	if len(matches) != 1 {
+		t.Errorf("got %v want only the sigil match", matches)
+	}
 }
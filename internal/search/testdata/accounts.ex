@@ -0,0 +1,18 @@
+defmodule TestApp.Accounts do
+  import TestApp.Accounts.Users, only: [get_user!: 1]
+
+  defdelegate fetch_user!(id), to: TestApp.Accounts.Users, as: :get_user!
+
+  def load(ids), do: Enum.map(ids, &TestApp.Accounts.Users.get_user!/1)
+
+  def load_one(id) do
+    id
+    |> get_user!()
+  end
+end
+
+defmodule TestApp.Accounts.Admin do
+  import TestApp.Accounts.Users
+
+  def fetch(id), do: get_user!(id)
+end
@@ -0,0 +1,31 @@
+defmodule TestApp.Accounts.Profile do
+  @moduledoc ~S"""
+  Profiles for #{user} accounts
+  """
+
+  @typedoc "A user profile"
+  @type t :: %__MODULE__{bio: String.t()}
+
+  defstruct [:bio]
+
+  @doc since: "1.2.0"
+  @doc """
+  Builds a profile for a user
+  """
+  @spec new(String.t()) :: t
+  def new(bio), do: %__MODULE__{bio: bio}
+
+  @doc false
+  def internal_bio(profile), do: profile.bio
+
+  def display_bio(profile), do: profile.bio
+  def display_bio(profile, max), do: String.slice(profile.bio, 0, max)
+
+  defp trim(bio), do: String.trim(bio)
+end
+
+defmodule TestApp.Accounts.Profile.Cache do
+  @moduledoc false
+
+  def fetch(id), do: id
+end
@@ -0,0 +1,28 @@
+defmodule TestApp.Accounts.Lookup do
+  @moduledoc """
+  Looks up accounts.
+
+      iex> TestApp.Accounts.Lookup.ready?()
+      true
+  """
+
+  alias TestApp.Accounts.Users
+
+  @doc """
+  Finds a user by id.
+
+  ## Examples
+
+      iex> user = Users.get_user!(1)
+      iex> user.username
+      "alice"
+
+      iex> Lookup.find(1,
+      ...>   preload: true
+      ...> )
+      %User{id: 1}
+  """
+  def find(id, opts \\ []), do: Users.get_user!(id)
+
+  def ready?, do: true
+end
@@ -0,0 +1,8 @@
+defmodule TestApp.Notifier do
+  @moduledoc """
+  Behaviour for sending notifications
+  """
+
+  @callback notify(user :: term, message :: String.t()) :: :ok | {:error, term}
+  @callback channel() :: atom
+end
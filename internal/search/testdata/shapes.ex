@@ -0,0 +1,15 @@
+defmodule TestApp.Shapes do
+  defmodule Circle do
+    defstruct [:radius]
+
+    def area(%Circle{radius: r}), do: 3.14 * r * r
+  end
+
+  def describe(%Circle{}), do: "circle"
+
+  def describe(_shape) do
+    "unknown"
+  end
+
+  defp log(message), do: IO.puts(message)
+end
@@ -0,0 +1,16 @@
+defmodule TestAppWeb.UserComponent do
+  use Phoenix.Component
+
+  @username_format ~r/^[a-z_]+$/iu
+  @roles ~w(admin user guest)a
+
+  def username(assigns) do
+    ~H"""
+    <span class="username">{@user.username}</span>
+    """
+  end
+
+  def valid_username?(username), do: username =~ @username_format
+
+  def default_role, do: List.to_atom('guest')
+end
@@ -0,0 +1,13 @@
+defprotocol TestApp.Sizer do
+  @doc "Calculates the size of a data structure"
+  def size(data)
+end
+
+defimpl TestApp.Sizer, for: Map do
+  def size(map), do: map_size(map)
+end
+
+defimpl TestApp.Sizer, for: [List, TestApp.Accounts.User] do
+  def size(list) when is_list(list), do: length(list)
+  def size(_user), do: 1
+end
@@ -0,0 +1,8 @@
+defmodule TestApp.Accounts.User do
+  @derive [Inspect, {Jason.Encoder, only: [:id, :username]}]
+  defstruct [:id, :username, :favorite_fruit]
+
+  defimpl String.Chars do
+    def to_string(user), do: user.username
+  end
+end
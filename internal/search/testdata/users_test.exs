@@ -0,0 +1,41 @@
+defmodule TestApp.Accounts.UsersTest do
+  use TestApp.DataCase, async: true
+
+  alias TestApp.Accounts.Users
+  alias TestApp.Repo
+
+  @moduletag :accounts
+
+  doctest TestApp.Accounts.Users
+
+  setup :create_user
+
+  describe "get_user!/1" do
+    test "returns the user", %{user: user} do
+      assert Users.get_user!(user.id) == user
+    end
+
+    @tag :integration
+    test "raises for missing users" do
+      assert_raise Ecto.NoResultsError, fn -> Users.get_user!(-1) end
+    end
+  end
+
+  describe "update_user/2" do
+    @describetag :integration
+
+    setup do
+      {:ok, attrs: %{username: "bob"}}
+    end
+
+    @tag slow: true
+    test "updates the username", %{user: user, attrs: attrs} do
+      {:ok, user} = Users.update_user(user, attrs)
+      assert Repo.reload(user).username == "bob"
+    end
+  end
+
+  defp create_user(_context) do
+    {:ok, user: Repo.insert!(%TestApp.Accounts.User{username: "alice"})}
+  end
+end
@@ -0,0 +1,32 @@
+defmodule TestApp.EmailWorker do
+  use GenServer
+
+  alias TestApp.Notifier
+
+  @behaviour Notifier
+
+  def start_link(opts) do
+    GenServer.start_link(__MODULE__, opts, name: __MODULE__)
+  end
+
+  @impl GenServer
+  def init(state), do: {:ok, state}
+
+  @impl true
+  def handle_cast({:send, user, message}, state) do
+    notify(user, message)
+    {:noreply, state}
+  end
+
+  @impl Notifier
+  def notify(user, message) do
+    IO.puts("#{user}: #{message}")
+    :ok
+  end
+
+  @impl true
+  def channel, do: :email
+
+  @impl Notifier
+  def deliver(user), do: user
+end
@@ -0,0 +1,159 @@
+package search
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// UnusedInput holds the input for finding unused functions.
+type UnusedInput struct {
+	Dir string
+
+	// Ignore skips functions matching one of these Module.function/arity patterns, where
+	// * matches any characters.
+	Ignore []string
+}
+
+// templateExts are the extensions of templates that can call functions
+var templateExts = []string{".eex", ".heex", ".leex", ".sface"}
+
+// templateSigils are sigils holding templates in elixir files
+var templateSigils = []string{"~H", "~E", "~L", "~F"}
+
+// templateCallRegex matches function calls like fun( and Mod.fun( and components like
+// <.fun in templates
+var templateCallRegex = regexp.MustCompile(`<\.([a-z_][A-Za-z0-9_]*)|\b([a-z_][A-Za-z0-9_]*[?!]?)\(`)
+
+// Unused prints every function in the project that is never referenced, grouped by file.
+func Unused(input *UnusedInput) error {
+	files, err := indexProject(input.Dir)
+	if err != nil {
+		return err
+	}
+
+	templateNames, err := parseTemplateNames(input.Dir, files)
+	if err != nil {
+		return err
+	}
+
+	found := findUnused(files, templateNames, compileModulePatterns(input.Ignore))
+	for _, file := range files {
+		defs := found[file.Path]
+		if len(defs) == 0 {
+			continue
+		}
+
+		relFile, err := filepath.Rel(input.Dir, file.Path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(relFile)
+		for _, def := range defs {
+			fmt.Println(def.Format())
+		}
+		fmt.Println("")
+	}
+
+	return nil
+}
+
+// findUnused finds the functions in each file without a reference anywhere in the project,
+// keyed by file path. References a function makes to itself don't count. Callbacks marked
+// with @impl or in a defimpl, controller actions, functions whose name is used in a
+// template and functions defined in .exs files like tests and mix.exs are never reported.
+func findUnused(files []*FileIndex, templateNames map[string]bool, ignore []*regexp.Regexp) map[string][]FuncDef {
+	refs := map[string][]Ref{}
+	callbacks := map[string]bool{}
+	for _, file := range files {
+		for _, ref := range file.Refs {
+			if ref.Module != "" {
+				refs[ref.Module+"."+ref.Name] = append(refs[ref.Module+"."+ref.Name], ref)
+			}
+		}
+
+		for _, impl := range file.Impls {
+			callbacks[impl] = true
+		}
+	}
+
+	found := map[string][]FuncDef{}
+	for _, file := range files {
+		if filepath.Ext(file.Path) == ".exs" {
+			continue
+		}
+
+		seen := map[string]bool{}
+		for _, def := range file.Defs {
+			if def.Kind != "def" && def.Kind != "defp" && def.Kind != "defdelegate" {
+				continue
+			}
+
+			// functions with several clauses are only reported once
+			mfa := def.Module + "." + def.Signature()
+			if seen[mfa] {
+				continue
+			}
+			seen[mfa] = true
+
+			isAction := def.Kind == "def" && def.Arity == 2 && strings.HasSuffix(def.Module, "Controller")
+			if callbacks[mfa] || isAction || templateNames[def.Name] || matchesAnyPattern(mfa, ignore) {
+				continue
+			}
+
+			used := slices.ContainsFunc(refs[def.Module+"."+def.Name], func(ref Ref) bool {
+				return ref.Caller != mfa && (ref.Arity < 0 || def.HasArity(ref.Arity))
+			})
+			if !used {
+				found[file.Path] = append(found[file.Path], def)
+			}
+		}
+	}
+
+	return found
+}
+
+// parseTemplateNames collects the names of the functions called from templates in the
+// project and from template sigils like ~H. Templates aren't parsed, so any name that
+// looks like a call counts.
+func parseTemplateNames(dir string, files []*FileIndex) (map[string]bool, error) {
+	names := map[string]bool{}
+	addNames := func(template string) {
+		for _, match := range templateCallRegex.FindAllStringSubmatch(template, -1) {
+			names[match[1]+match[2]] = true
+		}
+	}
+
+	for _, file := range files {
+		for _, str := range file.Strings {
+			if slices.ContainsFunc(templateSigils, func(sigil string) bool { return strings.HasPrefix(str, sigil) }) {
+				addNames(str)
+			}
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		if !entry.Type().IsRegular() || !slices.Contains(templateExts, filepath.Ext(entry.Name())) {
+			return nil
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		addNames(string(contents))
+		return nil
+	})
+
+	return names, err
+}
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var unusedProject = map[string]string{
+	"helpers.ex": `defmodule App.Helpers do
+  def used(x), do: loop(x)
+  def dead(x), do: dead(x - 1)
+  def applied(a, b), do: {a, b}
+  def dynamic(a), do: a
+  def in_template(x), do: x
+  def component(assigns), do: assigns
+  def ignored, do: nil
+
+  defp loop(0), do: 0
+  defp loop(n), do: loop(n - 1)
+  defp unused_private, do: nil
+  defdelegate format(x), to: App.Format
+end
+`,
+	"worker.ex": `defmodule App.Worker do
+  @behaviour App.Job
+
+  @impl true
+  def perform(job), do: Enum.map(job, &App.Worker.run/1)
+
+  def run(x) do
+    App.Helpers.used(x)
+    apply(App.Helpers, :applied, [x, x])
+    Kernel.apply(App.Helpers, :dynamic, [x] ++ [])
+  end
+
+  defimpl String.Chars do
+    def to_string(worker), do: inspect(worker)
+  end
+
+  def render(assigns) do
+    ~H"""
+    <.component /> {in_template(@x)}
+    """
+  end
+end
+`,
+	"user_controller.ex": `defmodule App.UserController do
+  def show(conn, _params), do: conn
+  def helper(conn), do: conn
+end
+`,
+	"worker_test.exs": `defmodule App.WorkerTest do
+  def setup_worker, do: nil
+end
+`,
+}
+
+func TestFindUnused(t *testing.T) {
+	dir := writeProjectFiles(t, unusedProject)
+	files, err := indexProject(dir)
+	if err != nil {
+		t.Fatalf("index project failed: %v", err)
+	}
+
+	names, err := parseTemplateNames(dir, files)
+	if err != nil {
+		t.Fatalf("parse template names failed: %v", err)
+	}
+
+	found := findUnused(files, names, compileModulePatterns([]string{"App.Helpers.ignored/*"}))
+
+	got := []string{}
+	for _, file := range files {
+		for _, def := range found[file.Path] {
+			got = append(got, def.Module+"."+def.Signature())
+		}
+	}
+
+	expected := []string{
+		"App.Helpers.dead/1",
+		"App.Helpers.unused_private/0",
+		"App.Helpers.format/1",
+		"App.UserController.helper/1",
+		"App.Worker.render/1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v want %v", got, expected)
+	}
+}
+
+func TestApplyRefs(t *testing.T) {
+	dir := writeProjectFiles(t, unusedProject)
+	found, err := findRefs(mustIndex(t, dir), "App.Helpers.dynamic/1")
+	if err != nil {
+		t.Fatalf("find refs failed: %v", err)
+	}
+
+	refs := found[filepath.Join(dir, "worker.ex")]
+	if len(refs) != 1 || refs[0].Kind != "apply" || refs[0].Arity != -1 {
+		t.Errorf("got %+v want the apply with an unknown arity", refs)
+	}
+}
+
+func mustIndex(t *testing.T, dir string) []*FileIndex {
+	files, err := indexProject(dir)
+	if err != nil {
+		t.Fatalf("index project failed: %v", err)
+	}
+
+	return files
+}
@@ -0,0 +1,588 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/elixir"
+)
+
+// editors often save a file with several writes, so changes are only picked up once the
+// file system has been quiet for this long.
+const watchDebounce = 100 * time.Millisecond
+
+// WatchInput holds all of the input necessary to watch a project.
+type WatchInput struct {
+	Dir string
+
+	// Query is run again every time a file changes when it isn't nil, and the difference
+	// in its results is printed.
+	Query *SearchInput
+
+	// Poll checks for changes every Interval instead of listening for file system events.
+	Poll     bool
+	Interval time.Duration
+}
+
+// parsedFile is the tree of a file that changed while watching, kept so the next change to
+// the file can be parsed incrementally.
+type parsedFile struct {
+	tree     *sitter.Tree
+	contents []byte
+}
+
+// watcher keeps the index of a project up to date as its files change.
+type watcher struct {
+	dir     string
+	persist bool // save the on disk index after every update
+	parser  *sitter.Parser
+	files   map[string]*FileIndex  // keyed by path, as the files are on disk
+	overlay map[string]*FileIndex  // files indexed from unsaved contents, keyed by path
+	parsed  map[string]*parsedFile // keyed by path
+}
+
+// newWatcher indexes a project, using the on disk index when there is one. When persist is
+// set the on disk index is brought up to date, and created if the project doesn't have one.
+func newWatcher(dir string, persist bool) (*watcher, error) {
+	index, err := loadIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, _, err := refreshIndex(dir, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if persist {
+		if err := saveIndex(dir, files); err != nil {
+			return nil, err
+		}
+	}
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(elixir.GetLanguage())
+
+	w := &watcher{
+		dir:     dir,
+		persist: persist,
+		parser:  parser,
+		files:   map[string]*FileIndex{},
+		overlay: map[string]*FileIndex{},
+		parsed:  map[string]*parsedFile{},
+	}
+	for _, file := range files {
+		w.files[file.Path] = file
+	}
+
+	resolveImports(files)
+	return w, nil
+}
+
+// update indexes the files that changed on disk since the last update and saves the index.
+// Unsaved contents are kept in the overlay and never saved. It returns the paths, relative
+// to the project, of files that were changed or deleted.
+func (w *watcher) update() ([]string, error) {
+	changed := []string{}
+	touched := false
+	seen := map[string]bool{}
+
+	err := walkElixirFiles(w.dir, func(path string) error {
+		seen[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		cached := w.files[path]
+		if cached != nil && cached.ModTime == info.ModTime().UnixNano() && cached.Size == info.Size() {
+			return nil
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		hash := contentHash(contents)
+		if cached != nil && cached.Hash == hash {
+			cached.ModTime = info.ModTime().UnixNano()
+			cached.Size = info.Size()
+			touched = true
+			return nil
+		}
+
+		root, err := w.parse(path, contents)
+		if err != nil {
+			return err
+		}
+
+		file, err := indexTree(path, root, contents)
+		if err != nil {
+			return err
+		}
+
+		file.Hash = hash
+		file.ModTime = info.ModTime().UnixNano()
+		file.Size = info.Size()
+		w.files[path] = file
+
+		changed = append(changed, w.relPath(path))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for path := range w.files {
+		if !seen[path] {
+			delete(w.files, path)
+			delete(w.parsed, path)
+			changed = append(changed, w.relPath(path))
+		}
+	}
+
+	if len(changed) == 0 && !touched {
+		return changed, nil
+	}
+
+	sort.Strings(changed)
+	resolveImports(w.indexedFiles())
+
+	if !w.persist {
+		return changed, nil
+	}
+
+	return changed, saveIndex(w.dir, sortedFiles(w.files))
+}
+
+// updateContents indexes contents that haven't been saved to a file yet, like a file being
+// edited, in place of the file on disk until removeContents is called. Contents that were
+// already indexed aren't parsed again.
+func (w *watcher) updateContents(path string, contents []byte) error {
+	hash := contentHash(contents)
+	if file := w.overlay[path]; file != nil && file.Hash == hash {
+		return nil
+	}
+
+	// contents matching the file on disk don't need to be overlaid
+	if file := w.files[path]; file != nil && file.Hash == hash {
+		w.removeContents(path)
+		return nil
+	}
+
+	root, err := w.parse(path, contents)
+	if err != nil {
+		return err
+	}
+
+	file, err := indexTree(path, root, contents)
+	if err != nil {
+		return err
+	}
+
+	file.Hash = hash
+	w.overlay[path] = file
+
+	resolveImports(w.indexedFiles())
+	return nil
+}
+
+// removeContents goes back to indexing a file from disk, like when a file being edited is
+// closed.
+func (w *watcher) removeContents(path string) {
+	if w.overlay[path] == nil {
+		return
+	}
+
+	delete(w.overlay, path)
+	resolveImports(w.indexedFiles())
+}
+
+// file returns the index of a file, from its unsaved contents when it has any
+func (w *watcher) file(path string) *FileIndex {
+	if file := w.overlay[path]; file != nil {
+		return file
+	}
+
+	return w.files[path]
+}
+
+// index returns the index of every file keyed by path, using unsaved contents over the
+// files on disk.
+func (w *watcher) index() map[string]*FileIndex {
+	if len(w.overlay) == 0 {
+		return w.files
+	}
+
+	files := maps.Clone(w.files)
+	maps.Copy(files, w.overlay)
+	return files
+}
+
+// indexedFiles returns the index of every file sorted by path, using unsaved contents over
+// the files on disk.
+func (w *watcher) indexedFiles() []*FileIndex {
+	return sortedFiles(w.index())
+}
+
+func sortedFiles(index map[string]*FileIndex) []*FileIndex {
+	files := []*FileIndex{}
+	for _, file := range index {
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// parse parses the new contents of a file. Files that have already been parsed while
+// watching are parsed incrementally from their previous tree.
+func (w *watcher) parse(path string, contents []byte) (*sitter.Node, error) {
+	var oldTree *sitter.Tree
+	if prev := w.parsed[path]; prev != nil {
+		prev.tree.Edit(treeEdit(prev.contents, contents))
+		oldTree = prev.tree
+	}
+
+	tree, err := w.parser.ParseCtx(context.Background(), oldTree, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	w.parsed[path] = &parsedFile{tree: tree, contents: contents}
+	return tree.RootNode(), nil
+}
+
+func (w *watcher) relPath(path string) string {
+	if relPath, err := filepath.Rel(w.dir, path); err == nil {
+		return relPath
+	}
+	return path
+}
+
+// treeEdit describes the change from before to after as a single edit covering everything
+// between their common prefix and suffix.
+func treeEdit(before, after []byte) sitter.EditInput {
+	start := 0
+	for start < len(before) && start < len(after) && before[start] == after[start] {
+		start++
+	}
+
+	suffix := 0
+	for suffix < len(before)-start && suffix < len(after)-start &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	oldEnd := len(before) - suffix
+	newEnd := len(after) - suffix
+
+	return sitter.EditInput{
+		StartIndex:  uint32(start),
+		OldEndIndex: uint32(oldEnd),
+		NewEndIndex: uint32(newEnd),
+		StartPoint:  pointAt(before, start),
+		OldEndPoint: pointAt(before, oldEnd),
+		NewEndPoint: pointAt(after, newEnd),
+	}
+}
+
+// pointAt converts a byte offset into a row and byte column.
+func pointAt(contents []byte, offset int) sitter.Point {
+	row := bytes.Count(contents[:offset], []byte("\n"))
+	column := offset - (bytes.LastIndexByte(contents[:offset], '\n') + 1)
+	return sitter.Point{Row: uint32(row), Column: uint32(column)}
+}
+
+// watchChanges signals on the returned channel whenever elixir files under dir might have
+// changed. It listens for file system events, or polls when that isn't possible.
+func watchChanges(ctx context.Context, input *WatchInput) (<-chan struct{}, <-chan error, error) {
+	changes := make(chan struct{}, 1)
+	errs := make(chan error, 1)
+
+	signal := func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+
+	if !input.Poll {
+		events, err := fsnotify.NewWatcher()
+		if err == nil {
+			if err := watchDirs(events, input.Dir); err != nil {
+				events.Close()
+				return nil, nil, err
+			}
+
+			go watchEvents(ctx, events, signal, errs)
+			return changes, errs, nil
+		}
+
+		fmt.Fprintf(os.Stderr, "Can't watch for file system events, polling instead: %v\n", err)
+	}
+
+	go func() {
+		interval := input.Interval
+		if interval <= 0 {
+			interval = time.Second
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				signal()
+			}
+		}
+	}()
+
+	return changes, errs, nil
+}
+
+// watchEvents turns file system events for elixir files into change signals, watching new
+// directories as they're created.
+func watchEvents(ctx context.Context, events *fsnotify.Watcher, signal func(), errs chan<- error) {
+	defer events.Close()
+
+	debounce := time.AfterFunc(time.Hour, signal)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-events.Errors:
+			errs <- err
+			return
+		case event := <-events.Events:
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := watchDirs(events, event.Name); err != nil {
+						errs <- err
+						return
+					}
+					debounce.Reset(watchDebounce)
+					continue
+				}
+			}
+
+			// deleting or renaming a directory doesn't send events for the files inside it
+			ext := filepath.Ext(event.Name)
+			if ext == ".ex" || ext == ".exs" || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				debounce.Reset(watchDebounce)
+			}
+		}
+	}
+}
+
+// watchDirs watches dir and every directory under it. Hidden directories like .git and the
+// index directory aren't watched.
+func watchDirs(events *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if path != dir && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		return events.Add(path)
+	})
+}
+
+// Watch keeps the on disk index of a project up to date until ctx is done, printing the
+// files that change. When the input has a query it's run once up front, then again after
+// every change, printing the results that were removed and added.
+func Watch(ctx context.Context, input *WatchInput) error {
+	w, err := newWatcher(input.Dir, true)
+	if err != nil {
+		return err
+	}
+
+	var last []FileResults
+	if input.Query != nil {
+		last, err = searchFiles(input.Query, w.files)
+		if err != nil {
+			return err
+		}
+
+		for _, res := range last {
+			fmt.Println(res.Path)
+			for _, match := range res.Matches {
+				fmt.Println(match)
+			}
+			fmt.Println("")
+		}
+		fmt.Printf("%d results\n", countResults(last))
+	}
+
+	changes, errs, err := watchChanges(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Watching %d files in %s\n", len(w.files), input.Dir)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			return err
+		case <-changes:
+		}
+
+		changed, err := w.update()
+		if err != nil {
+			return err
+		}
+
+		if len(changed) == 0 {
+			continue
+		}
+
+		for _, path := range changed {
+			fmt.Printf("Changed %s\n", path)
+		}
+
+		if input.Query == nil {
+			continue
+		}
+
+		results, err := searchFiles(input.Query, w.files)
+		if err != nil {
+			return err
+		}
+
+		diffs := diffResults(last, results)
+		for _, diff := range diffs {
+			fmt.Println(diff.Path)
+			for _, match := range diff.Removed {
+				fmt.Println(prefixLines("- ", match))
+			}
+			for _, match := range diff.Added {
+				fmt.Println(prefixLines("+ ", match))
+			}
+			fmt.Println("")
+		}
+		fmt.Printf("%d results (%d removed, %d added)\n", countResults(results), countRemoved(diffs), countAdded(diffs))
+
+		last = results
+	}
+}
+
+// ResultsDiff holds the matches removed from and added to a file between two searches.
+type ResultsDiff struct {
+	Path    string
+	Removed []string
+	Added   []string
+}
+
+var lineNumbers = regexp.MustCompile(`(?m)^(\s*)\d+:`)
+
+// diffResults compares the results of two searches file by file. Matches are compared
+// without their line numbers so code moving up or down a file isn't reported as a change.
+func diffResults(before, after []FileResults) []ResultsDiff {
+	paths := []string{}
+	beforeMatches := map[string][]string{}
+	afterMatches := map[string][]string{}
+
+	for _, res := range before {
+		paths = append(paths, res.Path)
+		beforeMatches[res.Path] = res.Matches
+	}
+	for _, res := range after {
+		if _, ok := beforeMatches[res.Path]; !ok {
+			paths = append(paths, res.Path)
+		}
+		afterMatches[res.Path] = res.Matches
+	}
+	sort.Strings(paths)
+
+	diffs := []ResultsDiff{}
+	for _, path := range paths {
+		diff := ResultsDiff{
+			Path:    path,
+			Removed: subtractMatches(beforeMatches[path], afterMatches[path]),
+			Added:   subtractMatches(afterMatches[path], beforeMatches[path]),
+		}
+
+		if len(diff.Removed) > 0 || len(diff.Added) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	return diffs
+}
+
+// subtractMatches returns the matches in a that aren't in b, counting duplicates.
+func subtractMatches(a, b []string) []string {
+	counts := map[string]int{}
+	for _, match := range b {
+		counts[lineNumbers.ReplaceAllString(match, "$1")]++
+	}
+
+	missing := []string{}
+	for _, match := range a {
+		key := lineNumbers.ReplaceAllString(match, "$1")
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		missing = append(missing, match)
+	}
+
+	return missing
+}
+
+func prefixLines(prefix, s string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+func countResults(results []FileResults) int {
+	count := 0
+	for _, res := range results {
+		count += len(res.Matches)
+	}
+	return count
+}
+
+func countRemoved(diffs []ResultsDiff) int {
+	count := 0
+	for _, diff := range diffs {
+		count += len(diff.Removed)
+	}
+	return count
+}
+
+func countAdded(diffs []ResultsDiff) int {
+	count := 0
+	for _, diff := range diffs {
+		count += len(diff.Added)
+	}
+	return count
+}
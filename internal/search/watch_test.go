@@ -0,0 +1,144 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWatcherUpdate(t *testing.T) {
+	dir := writeTestProject(t)
+	w, err := newWatcher(dir, true)
+	if err != nil {
+		t.Fatalf("new watcher failed: %v", err)
+	}
+
+	if _, err := os.Stat(indexPath(dir)); err != nil {
+		t.Errorf("expected the index to be created: %v", err)
+	}
+
+	if changed, _ := w.update(); len(changed) != 0 {
+		t.Errorf("got %v changed files want none", changed)
+	}
+
+	// edit the same file twice so the second edit is parsed incrementally
+	path := filepath.Join(dir, "users.ex")
+	contents := mustRead(t, path)
+	for _, edit := range []string{"\n  # a comment\n", "\n\n  def extra, do: TestApp.Users.process(:extra)\n"} {
+		at := strings.Index(string(contents), "\n")
+		contents = append(contents[:at:at], append([]byte(edit), contents[at:]...)...)
+		if err := os.WriteFile(path, contents, 0o644); err != nil {
+			t.Fatalf("Unable to write file, %v", err)
+		}
+
+		changed, err := w.update()
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+
+		if !reflect.DeepEqual(changed, []string{"users.ex"}) {
+			t.Errorf("got %v changed files want [users.ex]", changed)
+		}
+	}
+
+	// incremental parsing gives the same index as parsing from scratch
+	expected, err := indexFile(path, contents)
+	if err != nil {
+		t.Fatalf("index file failed: %v", err)
+	}
+
+	got := w.files[path]
+	if !reflect.DeepEqual(got.Refs, expected.Refs) || !reflect.DeepEqual(got.Calls, expected.Calls) {
+		t.Errorf("got %+v want %+v", got, expected)
+	}
+
+	// the on disk index is kept up to date
+	index, _ := loadIndex(dir)
+	if index.Files["users.ex"] == nil || index.Files["users.ex"].Hash != got.Hash {
+		t.Errorf("expected the on disk index to have the change")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "accounts.ex")); err != nil {
+		t.Fatalf("Unable to remove file, %v", err)
+	}
+
+	changed, _ := w.update()
+	if !reflect.DeepEqual(changed, []string{"accounts.ex"}) || w.files[filepath.Join(dir, "accounts.ex")] != nil {
+		t.Errorf("got %v changed files want [accounts.ex] to be dropped", changed)
+	}
+}
+
+func TestWatcherUpdateContents(t *testing.T) {
+	dir := writeTestProject(t)
+	w, err := newWatcher(dir, true)
+	if err != nil {
+		t.Fatalf("new watcher failed: %v", err)
+	}
+
+	path := filepath.Join(dir, "users.ex")
+	disk := w.files[path]
+	contents := append([]byte("# unsaved\n"), mustRead(t, path)...)
+	if err := w.updateContents(path, contents); err != nil {
+		t.Fatalf("update contents failed: %v", err)
+	}
+
+	overlay := w.file(path)
+	if overlay == disk || overlay.Hash != contentHash(contents) || w.files[path] != disk {
+		t.Errorf("expected the unsaved contents to be indexed over the file on disk")
+	}
+
+	// unsaved contents aren't a change on disk, and the same contents aren't parsed again
+	if changed, _ := w.update(); len(changed) != 0 || w.files[path] != disk {
+		t.Errorf("got %v changed files want none", changed)
+	}
+
+	if err := w.updateContents(path, contents); err != nil || w.file(path) != overlay {
+		t.Errorf("expected the same contents to keep the overlay, %v", err)
+	}
+
+	index, _ := loadIndex(dir)
+	if index.Files["users.ex"].Hash != disk.Hash {
+		t.Errorf("expected unsaved contents to stay out of the on disk index")
+	}
+
+	w.removeContents(path)
+	if w.file(path) != disk {
+		t.Errorf("expected the file on disk after removing the unsaved contents")
+	}
+}
+
+func TestTreeEdit(t *testing.T) {
+	edit := treeEdit([]byte("def a do\n  b()\nend\n"), []byte("def a do\n  c()\n  d()\nend\n"))
+
+	if edit.StartIndex != 11 || edit.OldEndIndex != 12 || edit.NewEndIndex != 18 {
+		t.Errorf("got %+v", edit)
+	}
+
+	if edit.StartPoint.Row != 1 || edit.StartPoint.Column != 2 || edit.OldEndPoint.Row != 1 ||
+		edit.OldEndPoint.Column != 3 || edit.NewEndPoint.Row != 2 || edit.NewEndPoint.Column != 3 {
+		t.Errorf("got %+v", edit)
+	}
+}
+
+func TestDiffResults(t *testing.T) {
+	before := []FileResults{
+		{Path: "lib/a.ex", Matches: []string{"3:Repo.update(user)", "7:Repo.update(post)"}},
+		{Path: "lib/b.ex", Matches: []string{"1:Repo.update(comment)"}},
+	}
+	after := []FileResults{
+		{Path: "lib/a.ex", Matches: []string{"4:Repo.update(user)", "9:Repo.update(account)"}},
+		{Path: "lib/c.ex", Matches: []string{"2:Repo.update(tag)"}},
+	}
+
+	expected := []ResultsDiff{
+		{Path: "lib/a.ex", Removed: []string{"7:Repo.update(post)"}, Added: []string{"9:Repo.update(account)"}},
+		{Path: "lib/b.ex", Removed: []string{"1:Repo.update(comment)"}, Added: []string{}},
+		{Path: "lib/c.ex", Removed: []string{}, Added: []string{"2:Repo.update(tag)"}},
+	}
+
+	if diffs := diffResults(before, after); !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("got %+v want %+v", diffs, expected)
+	}
+}